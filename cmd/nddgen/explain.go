@@ -0,0 +1,133 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errInvalidTypeRef = "type must be supplied as <package>.<Type>"
+	errTypeNotFound   = "cannot find type"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <package>.<Type>",
+	Short: "explain why a type does or does not match a ndd resource shape.",
+	Long: "explain prints, for every ndd resource shape, which of the required fields the supplied type has and why any " +
+		"others did not match, for example because a field is missing, not embedded or of the wrong type.",
+	Example:      "  ndd-gen explain ./apis/intf/v1.Interface",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		i := strings.LastIndex(args[0], ".")
+		if i <= 0 || i == len(args[0])-1 {
+			return errors.Errorf("%s : %s", errInvalidTypeRef, args[0])
+		}
+		path, name := args[0][:i], args[0][i+1:]
+
+		groups, err := loadPackages([]string{path})
+		if err != nil {
+			return err
+		}
+		for _, variants := range groups {
+			pkg := variants[0].Package
+			// Types that do not type check are still explained.
+			for _, err := range pkg.Errors {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+			}
+			if pkg.Types == nil {
+				continue
+			}
+			o, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			return errors.Wrap(explain(os.Stdout, pkg, o), errWriteOutput)
+		}
+		return errors.Errorf("%s : %s", errTypeNotFound, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	explainCmd.Flags().StringArrayVarP(&tags, "tags", "", nil, "A comma separated list of build tags to load the package with.")
+	explainCmd.Flags().StringVarP(&dir, "dir", "", "", "The directory in which to load the package. Defaults to the working directory.")
+	explainCmd.Flags().StringArrayVarP(&env, "env", "", nil, "An environment variable, e.g. GOOS=linux, to load the package with. May be repeated.")
+}
+
+// explain writes a diagnosis of the supplied Object against every match.Shape
+// to the supplied writer.
+func explain(out io.Writer, p *packages.Package, o types.Object) error {
+	if _, err := fmt.Fprintf(out, "%s.%s\n", p.PkgPath, o.Name()); err != nil {
+		return err
+	}
+	for _, s := range match.Shapes() {
+		d := s.Explain(o)
+		verdict := "matches"
+		if !d.Satisfied {
+			verdict = "does not match"
+		}
+		if _, err := fmt.Fprintf(out, "  %s: %s\n", s.Name, verdict); err != nil {
+			return err
+		}
+		if !d.Satisfied && d.Fields == nil {
+			if _, err := fmt.Fprintf(out, "    %s\n", d.Reason); err != nil {
+				return err
+			}
+		}
+		if err := writeDiagnoses(out, d.Fields, 2); err != nil {
+			return err
+		}
+	}
+	if match.HasMarker(comments.In(p), runner.DisableMarker, "false")(o) {
+		if _, err := fmt.Fprintf(out, "  method generation is disabled by the +%s=false marker\n", runner.DisableMarker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDiagnoses(out io.Writer, ds []fields.Diagnosis, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	for _, d := range ds {
+		line := fmt.Sprintf("%s[ok] %s", indent, d.Description)
+		if !d.Satisfied {
+			line = fmt.Sprintf("%s[!!] %s: %s", indent, d.Description, d.Reason)
+		}
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+		if err := writeDiagnoses(out, d.Fields, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
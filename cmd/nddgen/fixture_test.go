@@ -0,0 +1,156 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// fixtureEnv loads fixture modules offline, resolving their dependencies to
+// the stub modules of testdata/stubs.
+var fixtureEnv = []string{"GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off"}
+
+const fixtureGoMod = `module example.org/fixture
+
+go 1.16
+
+require (
+	github.com/netw-device-driver/ndd-runtime v0.0.0
+	k8s.io/apimachinery v0.0.0
+)
+
+replace github.com/netw-device-driver/ndd-runtime => %s
+
+replace k8s.io/apimachinery => %s
+`
+
+const fixtureTypes = `package %s
+
+import (
+	nddv1 "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ThingSpec struct {
+	nddv1.ResourceSpec ` + "`json:\",inline\"`" + `
+	Name               string ` + "`json:\"name,omitempty\"`" + `
+}
+
+type ThingStatus struct {
+	nddv1.ResourceStatus ` + "`json:\",inline\"`" + `
+}
+
+type Thing struct {
+	metav1.TypeMeta   ` + "`json:\",inline\"`" + `
+	metav1.ObjectMeta ` + "`json:\"metadata,omitempty\"`" + `
+
+	Spec   ThingSpec   ` + "`json:\"spec,omitempty\"`" + `
+	Status ThingStatus ` + "`json:\"status,omitempty\"`" + `
+}
+
+type ThingList struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+	metav1.ListMeta ` + "`json:\"metadata,omitempty\"`" + `
+	Items           []Thing ` + "`json:\"items\"`" + `
+}
+`
+
+// writeFixture writes a module to a temporary directory that contains the
+// supplied number of packages, apis/v1 through apis/vN, each of which declares
+// a managed resource and its list. It returns the directory.
+func writeFixture(tb testing.TB, packages int) string {
+	tb.Helper()
+	stubs, err := filepath.Abs(filepath.Join("testdata", "stubs"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	dir := tb.TempDir()
+	files := map[string]string{
+		"go.mod": fmt.Sprintf(fixtureGoMod, filepath.Join(stubs, "ndd-runtime"), filepath.Join(stubs, "apimachinery")),
+	}
+	for i := 1; i <= packages; i++ {
+		pkg := fmt.Sprintf("v%d", i)
+		files[filepath.Join("apis", pkg, "types.go")] = fmt.Sprintf(fixtureTypes, pkg)
+	}
+	writeFiles(tb, dir, files)
+	return dir
+}
+
+// writeFiles writes the supplied files, keyed by their path relative to the
+// supplied directory, replacing any that exist.
+func writeFiles(tb testing.TB, dir string, files map[string]string) {
+	tb.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil { // nolint:gosec
+			tb.Fatal(err)
+		}
+	}
+}
+
+// runGo runs the go command with the supplied arguments in the fixture module
+// in the supplied directory, failing the test if it fails. The test is skipped
+// if the go command is not found.
+func runGo(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	cmd := exec.Command(gobin, args...) // nolint:gosec
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), fixtureEnv...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go %v: %v\n%s", args, err, out)
+	}
+}
+
+// methodSetsConfig returns the configuration of a run of the generators of
+// generate-methodsets for every package of the fixture module in the supplied
+// directory, using the supplied number of jobs. Tests of the generated methods
+// are written if tests is true.
+func methodSetsConfig(dir string, tests bool, jobs int) runner.Config {
+	rc := runner.Config{
+		Name:   "generate-methodsets",
+		Paths:  []string{"./..."},
+		Dir:    dir,
+		Env:    fixtureEnv,
+		Jobs:   jobs,
+		Force:  true,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	for _, ms := range methodSets {
+		rc.Generators = append(rc.Generators, runner.Generator{
+			Name:     ms.name,
+			Defaults: config.Generator{Tests: &tests},
+			Generate: WithTests(ms.generate, ms.tests, ms.generated),
+		})
+	}
+	return rc
+}
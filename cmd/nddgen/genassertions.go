@@ -0,0 +1,163 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteAssertions = "cannot write interface assertions"
+	errLoadResourcePkg = "cannot load resource package"
+	errNoInterface     = "resource package %s has no interface %s"
+	errMissingMethods  = "%s does not implement %s.%s: missing %s"
+)
+
+var filenameAssertions string
+
+// assertions are the interfaces of the resource package that the types of a
+// package matched by each matcher must implement.
+var assertions = []struct {
+	iface   string
+	matches func(p *runner.Package) match.Object
+}{
+	{iface: "Managed", matches: (*runner.Package).Managed},
+	{iface: "ManagedList", matches: (*runner.Package).ManagedList},
+	{iface: "NetworkNode", matches: (*runner.Package).NetworkNode},
+	{iface: "NetworkNodeUsage", matches: (*runner.Package).NetworkNodeUsage},
+	{iface: "NetworkNodeUsageList", matches: (*runner.Package).NetworkNodeUsageList},
+}
+
+var genassertionsCmd = &cobra.Command{
+	Use:   "generate-assertions",
+	Short: "generate ndd interface assertions.",
+	Long: "generate compile-time assertions that every managed resource, NetworkNode and NetworkNodeUsage, and their " +
+		"lists, implement the corresponding interface of the resource package. Types that do not are reported, and " +
+		"no assertions are written for their package.",
+	Aliases:      []string{"gen-assertions"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorAssertions, flag: "filename-assertions", generate: GenerateAssertions},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genassertionsCmd)
+	addLoadFlags(genassertionsCmd)
+	genassertionsCmd.Flags().StringVarP(&filenameAssertions, "filename-assertions", "", "zz_generated.assertions.go", "The filename of generated interface assertion files.")
+	addOutputFlags(genassertionsCmd, "interface assertions")
+}
+
+// GenerateAssertions generates an assertion that every type of the supplied
+// package that is matched by one of the ndd matchers implements the
+// corresponding interface of the resource package.
+func GenerateAssertions(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	type assertion struct {
+		iface string
+		o     types.Object
+	}
+	var as []assertion
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		for _, a := range assertions {
+			if a.matches(p)(o) {
+				as = append(as, assertion{iface: a.iface, o: o})
+			}
+		}
+	}
+
+	var errs []string
+	if len(as) > 0 {
+		rp, err := p.Import(i.Resource.Path)
+		if err != nil {
+			return errors.Wrap(errors.Wrap(err, errLoadResourcePkg), errWriteAssertions)
+		}
+		for _, a := range as {
+			if err := checkImplements(a.o, rp, a.iface); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteAssertions)
+	}
+
+	err := generate.WriteCode(p.Package, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename), func(f *jen.File) {
+		defs := make([]jen.Code, 0, len(as))
+		for _, a := range as {
+			defs = append(defs, jen.Id("_").Qual(i.Resource.Path, a.iface).Op("=").Op("&").Id(a.o.Name()).Values())
+		}
+		if len(defs) == 0 {
+			return
+		}
+		f.Comment("Ensure the ndd resources of this package implement their interfaces.")
+		f.Var().Defs(defs...)
+	}, append([]generate.WriteOption{
+		generate.WithHeaders(header),
+		generate.WithImportAliases(map[string]string{i.Resource.Path: i.Resource.Alias}),
+	}, wo...)...)
+
+	return errors.Wrap(err, errWriteAssertions)
+}
+
+// checkImplements returns an error naming every method a pointer to the
+// supplied Object lacks to implement the named interface of the supplied
+// package.
+func checkImplements(o types.Object, rp *types.Package, iface string) error {
+	tn, ok := rp.Scope().Lookup(iface).(*types.TypeName)
+	if !ok {
+		return errors.Errorf(errNoInterface, rp.Path(), iface)
+	}
+	it, ok := tn.Type().Underlying().(*types.Interface)
+	if !ok {
+		return errors.Errorf(errNoInterface, rp.Path(), iface)
+	}
+	t := types.NewPointer(o.Type())
+	if types.Implements(t, it) {
+		return nil
+	}
+
+	// A resource package that was loaded separately does not share types with
+	// the supplied Object's package, so signatures are compared by name.
+	qualifier := func(p *types.Package) string { return p.Path() }
+	var missing []string
+	for m := 0; m < it.NumMethods(); m++ {
+		want := it.Method(m)
+		got, _, _ := types.LookupFieldOrMethod(t, false, want.Pkg(), want.Name())
+		fn, ok := got.(*types.Func)
+		if !ok || types.TypeString(fn.Type(), qualifier) != types.TypeString(want.Type(), qualifier) {
+			missing = append(missing, want.Name())
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.Errorf(errMissingMethods, o.Name(), rp.Name(), iface, strings.Join(missing, ", "))
+}
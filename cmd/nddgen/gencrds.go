@@ -0,0 +1,149 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteCRD = "cannot write CustomResourceDefinitions"
+)
+
+var crdDir string
+
+var gencrdsCmd = &cobra.Command{
+	Use:   "generate-crds",
+	Short: "generate ndd CustomResourceDefinitions.",
+	Long: "generate a CustomResourceDefinition, including its OpenAPI v3 schema, for every managed resource, " +
+		"NetworkNode and NetworkNodeUsage. Packages must declare their API group using the +" + crd.MarkerGroupName + " marker.",
+	Aliases:      []string{"gen-crds"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorCRDs, dirFlag: "crd-dir", generate: GenerateCRDs, typeErrorsOK: true},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gencrdsCmd)
+	addLoadFlags(gencrdsCmd)
+	gencrdsCmd.Flags().StringVarP(&crdDir, "crd-dir", "", "package/crds", "The directory, relative to the module root, to which CustomResourceDefinitions are written.")
+	addOutputFlags(gencrdsCmd, "CustomResourceDefinitions")
+}
+
+// crdKinds are the kinds of resource for which generate-crds generates
+// CustomResourceDefinitions, along with the printer columns they get unless
+// they declare their own.
+var crdKinds = []struct {
+	matches func() match.Object
+	columns func() []crd.PrinterColumn
+}{
+	{matches: match.Managed, columns: ManagedPrinterColumns},
+	{matches: match.NetworkNode, columns: NetworkNodePrinterColumns},
+	{matches: match.NetworkNodeUsage, columns: NetworkNodeUsagePrinterColumns},
+}
+
+// GenerateCRDs generates a CustomResourceDefinition for every kind of
+// resource in the supplied package. They are written to the directory of the
+// supplied config.Generator. The supplied header is not used, since it is Go
+// source.
+func GenerateCRDs(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	dir := g.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.Root(), dir)
+	}
+
+	var b *crd.Builder
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o, ok := p.Types.Scope().Lookup(n).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		for _, k := range crdKinds {
+			if !k.matches()(o) {
+				continue
+			}
+			if b == nil {
+				var err error
+				if b, err = crd.NewBuilder(p.Package); err != nil {
+					return errors.Wrap(err, errWriteCRD)
+				}
+			}
+			if err := writeCRD(b, o, k.columns(), dir, wo...); err != nil {
+				errs = append(errs, err.Error())
+			}
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteCRD)
+	}
+	return nil
+}
+
+func writeCRD(b *crd.Builder, o types.Object, columns []crd.PrinterColumn, dir string, wo ...generate.WriteOption) error {
+	c, err := b.Build(o, columns)
+	if err != nil {
+		return err
+	}
+	data, err := crd.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return generate.WriteData(filepath.Join(dir, c.Spec.Group+"_"+c.Spec.Names.Plural+".yaml"), data, wo...)
+}
+
+// ManagedPrinterColumns returns the printer columns of a managed resource.
+func ManagedPrinterColumns() []crd.PrinterColumn {
+	return []crd.PrinterColumn{
+		{Name: "ACTIVE", Type: "boolean", JSONPath: ".spec.active"},
+		{Name: "READY", Type: "string", JSONPath: ".status.conditions[?(@.kind=='Ready')].status"},
+		{Name: "SYNCED", Type: "string", JSONPath: ".status.conditions[?(@.kind=='Synced')].status"},
+		{Name: "AGE", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	}
+}
+
+// NetworkNodePrinterColumns returns the printer columns of a NetworkNode.
+func NetworkNodePrinterColumns() []crd.PrinterColumn {
+	return []crd.PrinterColumn{
+		{Name: "READY", Type: "string", JSONPath: ".status.conditions[?(@.kind=='Ready')].status"},
+		{Name: "SYNCED", Type: "string", JSONPath: ".status.conditions[?(@.kind=='Synced')].status"},
+		{Name: "AGE", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	}
+}
+
+// NetworkNodeUsagePrinterColumns returns the printer columns of a
+// NetworkNodeUsage.
+func NetworkNodeUsagePrinterColumns() []crd.PrinterColumn {
+	return []crd.PrinterColumn{
+		{Name: "AGE", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	// DeepCopyMarker enables or disables deep copy generation. On a package
+	// it sets the default for all of its types, which is enabled, while on a
+	// type it overrides that default, e.g. +k8s:deepcopy-gen=false.
+	DeepCopyMarker = "k8s:deepcopy-gen"
+
+	// DeepCopyInterfacesMarker declares the interfaces a type implements using
+	// its deep copy methods. A type whose interfaces include runtime.Object
+	// gets a DeepCopyObject method.
+	DeepCopyInterfacesMarker = "k8s:deepcopy-gen:interfaces"
+
+	// ObjectRootMarker marks a type as the root of a Kubernetes object, which
+	// gets a DeepCopyObject method.
+	ObjectRootMarker = "kubebuilder:object:root"
+)
+
+const (
+	errWriteDeepCopyMethod = "cannot write deep copy methods"
+)
+
+var filenameDeepCopy string
+
+var gendeepcopyCmd = &cobra.Command{
+	Use:   "generate-deepcopy",
+	Short: "generate ndd deep copy methods.",
+	Long: "generate DeepCopy and DeepCopyInto methods for all struct, slice and map types, and DeepCopyObject methods " +
+		"for all ndd resources and other Kubernetes objects. Files previously generated by controller-gen must be " +
+		"removed before they can be replaced.",
+	Aliases:      []string{"gen-deepcopy"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorDeepCopy, flag: "filename-deepcopy", generate: GenerateDeepCopy, typeErrorsOK: true},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gendeepcopyCmd)
+	addLoadFlags(gendeepcopyCmd)
+	gendeepcopyCmd.Flags().StringVarP(&filenameDeepCopy, "filename-deepcopy", "", "zz_generated.deepcopy.go", "The filename of generated deep copy files.")
+	addOutputFlags(gendeepcopyCmd, "deep copy methods")
+}
+
+// GenerateDeepCopy generates the deep copy method set.
+func GenerateDeepCopy(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	generated := match.Memoize(match.AllOf(isDeepCopyType, deepCopyEnabled(p)))
+
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !generated(o) {
+			continue
+		}
+		if err := method.CanDeepCopy(o, generated); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteDeepCopyMethod)
+	}
+
+	object := match.AnyOf(
+		match.Managed(),
+		match.ManagedList(),
+		match.NetworkNode(),
+		match.NetworkNodeUsage(),
+		match.NetworkNodeUsageList(),
+		match.HasMarker(p.Comments, DeepCopyInterfacesMarker, i.KubeRuntime.Path+".Object"),
+		match.HasMarker(p.Comments, ObjectRootMarker, "true"),
+	)
+	methods := DeepCopyMethods(g.Receiver, i, generated, object)
+
+	err := generate.WriteMethods(p.Package, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{i.KubeRuntime.Path: i.KubeRuntime.Alias}),
+			generate.WithMatcher(generated),
+		}, wo...)...,
+	)
+
+	return errors.Wrap(err, errWriteDeepCopyMethod)
+}
+
+// DeepCopyMethods returns the deep copy method set. Deep copy methods are
+// written for the types matched by generated, while DeepCopyObject is only
+// written for those that are also matched by object.
+func DeepCopyMethods(receiver string, i config.Imports, generated, object match.Object) method.Set {
+	return method.Set{
+		"DeepCopyInto":   method.NewDeepCopyInto(receiver, generated),
+		"DeepCopy":       method.NewDeepCopy(receiver),
+		"DeepCopyObject": method.NewDeepCopyObject(receiver, i.KubeRuntime.Path, object),
+	}
+}
+
+// isDeepCopyType returns true if the supplied Object is a named struct, slice
+// or map type.
+func isDeepCopyType(o types.Object) bool {
+	tn, ok := o.(*types.TypeName)
+	if !ok || tn.IsAlias() {
+		return false
+	}
+	switch tn.Type().Underlying().(type) {
+	case *types.Struct, *types.Slice, *types.Map:
+		return true
+	}
+	return false
+}
+
+// deepCopyEnabled returns an Object matcher that returns true if deep copy
+// generation is enabled for the supplied Object, either by a DeepCopyMarker
+// on the Object or else by default for the supplied package.
+func deepCopyEnabled(p *runner.Package) match.Object {
+	byDefault := true
+	for _, v := range comments.ParseMarkers(comments.Package(p.Package))[DeepCopyMarker] {
+		byDefault = v != "false"
+	}
+	enabled := match.HasMarker(p.Comments, DeepCopyMarker, "true")
+	disabled := match.HasMarker(p.Comments, DeepCopyMarker, "false")
+	return func(o types.Object) bool {
+		switch {
+		case enabled(o):
+			return true
+		case disabled(o):
+			return false
+		}
+		return byDefault
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteDefaults = "cannot write defaulting methods"
+)
+
+var filenameDefaults string
+
+var gendefaultsCmd = &cobra.Command{
+	Use:   "generate-defaults",
+	Short: "generate ndd defaulting methods.",
+	Long: "generate a Default method for every managed resource that sets the unset fields of its spec to the default " +
+		"values declared by their +kubebuilder:default or +" + comments.MarkerDefault + " markers.",
+	Aliases:      []string{"gen-defaults"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorDefaults, flag: "filename-defaults", generate: GenerateDefaults},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gendefaultsCmd)
+	addLoadFlags(gendefaultsCmd)
+	gendefaultsCmd.Flags().StringVarP(&filenameDefaults, "filename-defaults", "", "zz_generated.defaults.go", "The filename of generated defaulting files.")
+	addOutputFlags(gendefaultsCmd, "defaulting methods")
+}
+
+// GenerateDefaults generates the Default method of every managed resource of
+// the supplied package.
+func GenerateDefaults(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	generated := p.Managed()
+
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !generated(o) {
+			continue
+		}
+		if err := method.CheckDefaults(p.Comments, o); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteDefaults)
+	}
+
+	err := generate.WriteMethods(p.Package, DefaultMethods(g.Receiver, p.Comments), filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithMatcher(generated),
+		}, wo...)...,
+	)
+
+	return errors.Wrap(err, errWriteDefaults)
+}
+
+// DefaultMethods returns the methods that default the spec of a managed
+// resource per the markers of its fields, per the supplied comments.
+func DefaultMethods(receiver string, c comments.Comments) method.Set {
+	return method.Set{
+		"Default": method.NewDefault(receiver, c),
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// defaultsTypes declares a managed resource whose spec has defaults of
+// pointers, slices, maps and structs, and of the fields nested within them.
+const defaultsTypes = `package v1
+
+import (
+	nddv1 "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +ndd:default=fast
+type Mode string
+
+type Nested struct {
+	// +kubebuilder:default=3
+	Replicas int ` + "`json:\"replicas,omitempty\"`" + `
+}
+
+type Item struct {
+	// +kubebuilder:default=10
+	Weight int32 ` + "`json:\"weight,omitempty\"`" + `
+}
+
+type ThingSpec struct {
+	nddv1.ResourceSpec ` + "`json:\",inline\"`" + `
+
+	// +kubebuilder:default=1500
+	MTU *uint16 ` + "`json:\"mtu,omitempty\"`" + `
+
+	// +ndd:default=main
+	Name *string ` + "`json:\"name,omitempty\"`" + `
+
+	Mode Mode ` + "`json:\"mode,omitempty\"`" + `
+
+	// +kubebuilder:default={a,b}
+	Names []string ` + "`json:\"names,omitempty\"`" + `
+
+	// +kubebuilder:default={"x": "y"}
+	Attrs map[string]string ` + "`json:\"attrs,omitempty\"`" + `
+
+	// +kubebuilder:default={}
+	Nested *Nested ` + "`json:\"nested,omitempty\"`" + `
+
+	// +kubebuilder:default={}
+	Inline Nested ` + "`json:\"inline\"`" + `
+
+	Items []Item ` + "`json:\"items,omitempty\"`" + `
+
+	ByName map[string]*Item ` + "`json:\"byName,omitempty\"`" + `
+}
+
+type ThingStatus struct {
+	nddv1.ResourceStatus ` + "`json:\",inline\"`" + `
+}
+
+type Thing struct {
+	metav1.TypeMeta   ` + "`json:\",inline\"`" + `
+	metav1.ObjectMeta ` + "`json:\"metadata,omitempty\"`" + `
+
+	Spec   ThingSpec   ` + "`json:\"spec,omitempty\"`" + `
+	Status ThingStatus ` + "`json:\"status,omitempty\"`" + `
+}
+`
+
+// defaultsTest checks the spec set by the generated Default method.
+const defaultsTest = `package v1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	mtu, name := uint16(1500), "main"
+	setMTU, setName := uint16(9000), "other"
+	cases := map[string]struct {
+		spec ThingSpec
+		want ThingSpec
+	}{
+		"Unset": {
+			spec: ThingSpec{
+				Items:  []Item{{}, {Weight: 1}},
+				ByName: map[string]*Item{"a": {}, "b": nil},
+			},
+			want: ThingSpec{
+				MTU:    &mtu,
+				Name:   &name,
+				Mode:   "fast",
+				Names:  []string{"a", "b"},
+				Attrs:  map[string]string{"x": "y"},
+				Nested: &Nested{Replicas: 3},
+				Inline: Nested{Replicas: 3},
+				Items:  []Item{{Weight: 10}, {Weight: 1}},
+				ByName: map[string]*Item{"a": {Weight: 10}, "b": nil},
+			},
+		},
+		"Set": {
+			spec: ThingSpec{
+				MTU:    &setMTU,
+				Name:   &setName,
+				Mode:   "slow",
+				Names:  []string{"c"},
+				Attrs:  map[string]string{"z": "z"},
+				Nested: &Nested{Replicas: 1},
+				Inline: Nested{Replicas: 2},
+			},
+			want: ThingSpec{
+				MTU:    &setMTU,
+				Name:   &setName,
+				Mode:   "slow",
+				Names:  []string{"c"},
+				Attrs:  map[string]string{"z": "z"},
+				Nested: &Nested{Replicas: 1},
+				Inline: Nested{Replicas: 2},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := &Thing{Spec: tc.spec}
+			v.Default()
+			if !reflect.DeepEqual(tc.want, v.Spec) {
+				t.Errorf("Default(): want %+v, got %+v", tc.want, v.Spec)
+			}
+		})
+	}
+}
+`
+
+// TestGenerateDefaults generates the Default method of a fixture module using
+// a receiver named like the local variables of the method, then runs a test
+// of the spec it sets.
+func TestGenerateDefaults(t *testing.T) {
+	dir := writeFixture(t, 1)
+	writeFiles(t, dir, map[string]string{
+		filepath.Join("apis", "v1", "types.go"):         defaultsTypes,
+		filepath.Join("apis", "v1", "defaults_test.go"): defaultsTest,
+		config.Filename: "generators:\n  " + config.GeneratorDefaults + ":\n    receiver: v\n",
+	})
+
+	err := runner.Run(runner.Config{
+		Name:       "generate-defaults",
+		Paths:      []string{"./..."},
+		Dir:        dir,
+		Env:        fixtureEnv,
+		Force:      true,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+		Generators: []runner.Generator{{Name: config.GeneratorDefaults, Generate: GenerateDefaults}},
+	})
+	if err != nil {
+		t.Fatalf("Run(...): %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "apis", "v1", "zz_generated.defaults.go")); err != nil {
+		t.Fatalf("Run(...): defaults not written: %v", err)
+	}
+
+	runGo(t, dir, "vet", "./...")
+	runGo(t, dir, "test", "./...")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"path/filepath"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteDiff = "cannot write diff methods"
+)
+
+var filenameDiff string
+
+var gendiffCmd = &cobra.Command{
+	Use:   "generate-diff",
+	Short: "generate ndd spec equality and diff methods.",
+	Long: "generate an Equal and a Diff method for the spec of every managed resource. Diff returns the JSON path and " +
+		"values of every field that differs from that of another spec; nil and empty slices and maps are equal. " +
+		"The " + method.FieldDiff + " type it returns is generated alongside it, unless the package declares one.",
+	Aliases:      []string{"gen-diff"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorDiff, flag: "filename-diff", generate: GenerateDiff},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gendiffCmd)
+	addLoadFlags(gendiffCmd)
+	gendiffCmd.Flags().StringVarP(&filenameDiff, "filename-diff", "", "zz_generated.diff.go", "The filename of generated diff files.")
+	addOutputFlags(gendiffCmd, "diff methods")
+}
+
+// GenerateDiff generates the Equal and Diff methods of the spec of every
+// managed resource of the supplied package, and the FieldDiff type returned by
+// the latter if the package does not declare it.
+func GenerateDiff(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	specs := p.ManagedSpecs()
+	file := filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename)
+	ms := DiffMethods(g.Receiver)
+
+	err := generate.WriteCode(p.Package, file, func(f *jen.File) {
+		var found bool
+		for _, n := range p.Types.Scope().Names() {
+			if specs(p.Types.Scope().Lookup(n)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+		if o := p.Types.Scope().Lookup(method.FieldDiff); o == nil || p.Fset.Position(o.Pos()).Filename == file {
+			method.WriteFieldDiff(f)
+		}
+		for _, n := range p.Types.Scope().Names() {
+			o := p.Types.Scope().Lookup(n)
+			if !specs(o) {
+				continue
+			}
+			ms.Write(f, o, method.DefinedOutside(p.Fset, file))
+		}
+	}, append([]generate.WriteOption{generate.WithHeaders(header)}, wo...)...)
+
+	return errors.Wrap(err, errWriteDiff)
+}
+
+// DiffMethods returns the methods that compare the spec of a managed resource
+// to another.
+func DiffMethods(receiver string) method.Set {
+	return method.Set{
+		"Diff":  method.NewDiff(receiver, "spec"),
+		"Equal": method.NewEqual(receiver),
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// diffTypes declares a managed resource whose spec has slices, maps and a
+// recursive type.
+const diffTypes = `package v1
+
+import (
+	nddv1 "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type Node struct {
+	Name     string ` + "`json:\"name\"`" + `
+	Children []Node ` + "`json:\"children,omitempty\"`" + `
+}
+
+type ThingSpec struct {
+	nddv1.ResourceSpec ` + "`json:\",inline\"`" + `
+
+	Name   string            ` + "`json:\"name\"`" + `
+	Tags   []string          ` + "`json:\"tags,omitempty\"`" + `
+	Labels map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	Tree   Node              ` + "`json:\"tree\"`" + `
+}
+
+type ThingStatus struct {
+	nddv1.ResourceStatus ` + "`json:\",inline\"`" + `
+}
+
+type Thing struct {
+	metav1.TypeMeta   ` + "`json:\",inline\"`" + `
+	metav1.ObjectMeta ` + "`json:\"metadata,omitempty\"`" + `
+
+	Spec   ThingSpec   ` + "`json:\"spec,omitempty\"`" + `
+	Status ThingStatus ` + "`json:\"status,omitempty\"`" + `
+}
+`
+
+// diffTest checks the paths of the FieldDiffs returned by the generated Diff
+// method.
+const diffTest = `package v1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cases := map[string]struct {
+		a, b ThingSpec
+		want []string
+	}{
+		"Equal": {
+			a: ThingSpec{Name: "a", Tags: []string{"x"}, Labels: map[string]string{"k": "v"}},
+			b: ThingSpec{Name: "a", Tags: []string{"x"}, Labels: map[string]string{"k": "v"}},
+		},
+		"NilAndEmptySlice": {
+			a: ThingSpec{Tags: nil},
+			b: ThingSpec{Tags: []string{}},
+		},
+		"NilAndEmptyMap": {
+			a: ThingSpec{Labels: nil},
+			b: ThingSpec{Labels: map[string]string{}},
+		},
+		"SliceLength": {
+			a:    ThingSpec{Tags: []string{"x"}},
+			b:    ThingSpec{Tags: []string{"x", "y"}},
+			want: []string{"spec.tags"},
+		},
+		"SliceItem": {
+			a:    ThingSpec{Tags: []string{"x", "y"}},
+			b:    ThingSpec{Tags: []string{"x", "z"}},
+			want: []string{"spec.tags[1]"},
+		},
+		"MapValue": {
+			a:    ThingSpec{Labels: map[string]string{"k": "v"}},
+			b:    ThingSpec{Labels: map[string]string{"k": "w"}},
+			want: []string{"spec.labels[k]"},
+		},
+		"KeyMissingFromOther": {
+			a:    ThingSpec{Labels: map[string]string{"k": "v", "l": "v"}},
+			b:    ThingSpec{Labels: map[string]string{"k": "v"}},
+			want: []string{"spec.labels[l]"},
+		},
+		"KeyMissingFromThis": {
+			a:    ThingSpec{Labels: map[string]string{"k": "v"}},
+			b:    ThingSpec{Labels: map[string]string{"k": "v", "l": "v"}},
+			want: []string{"spec.labels[l]"},
+		},
+		"Sorted": {
+			a:    ThingSpec{Name: "a", Tags: []string{"x"}, Labels: map[string]string{"z": "v", "b": "v", "m": "v"}},
+			b:    ThingSpec{Name: "b", Labels: map[string]string{"a": "v"}},
+			want: []string{"spec.labels[a]", "spec.labels[b]", "spec.labels[m]", "spec.labels[z]", "spec.name", "spec.tags"},
+		},
+		"RecursiveEqual": {
+			a: ThingSpec{Tree: Node{Name: "root", Children: []Node{{Name: "leaf", Children: []Node{{}}}}}},
+			b: ThingSpec{Tree: Node{Name: "root", Children: []Node{{Name: "leaf", Children: []Node{{}}}}}},
+		},
+		"Recursive": {
+			a:    ThingSpec{Tree: Node{Name: "root", Children: []Node{{Name: "leaf", Children: []Node{{Name: "x"}}}}}},
+			b:    ThingSpec{Tree: Node{Name: "root", Children: []Node{{Name: "leaf", Children: []Node{{Name: "y"}}}}}},
+			want: []string{"spec.tree.children[0]"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// Maps are iterated in no particular order, so compare a few
+			// times to catch unsorted results.
+			for i := 0; i < 10; i++ {
+				var got []string
+				for _, d := range tc.a.Diff(&tc.b) {
+					got = append(got, d.Path)
+				}
+				if !reflect.DeepEqual(tc.want, got) {
+					t.Fatalf("Diff(...): want paths %v, got %v", tc.want, got)
+				}
+				if equal := tc.a.Equal(&tc.b); equal != (len(tc.want) == 0) {
+					t.Fatalf("Equal(...): want %t, got %t", len(tc.want) == 0, equal)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffMissingKey(t *testing.T) {
+	a := ThingSpec{Labels: map[string]string{"k": "v"}}
+	b := ThingSpec{}
+	want := []FieldDiff{{Path: "spec.labels[k]", Value: "v", Other: nil}}
+	if got := a.Diff(&b); !reflect.DeepEqual(want, got) {
+		t.Errorf("a.Diff(b): want %+v, got %+v", want, got)
+	}
+	want = []FieldDiff{{Path: "spec.labels[k]", Value: nil, Other: "v"}}
+	if got := b.Diff(&a); !reflect.DeepEqual(want, got) {
+		t.Errorf("b.Diff(a): want %+v, got %+v", want, got)
+	}
+}
+`
+
+// TestGenerateDiff generates the Diff and Equal methods of a fixture module,
+// then runs a test of the FieldDiffs they return.
+func TestGenerateDiff(t *testing.T) {
+	dir := writeFixture(t, 1)
+	writeFiles(t, dir, map[string]string{
+		filepath.Join("apis", "v1", "types.go"):     diffTypes,
+		filepath.Join("apis", "v1", "diff_test.go"): diffTest,
+	})
+
+	err := runner.Run(runner.Config{
+		Name:       "generate-diff",
+		Paths:      []string{"./..."},
+		Dir:        dir,
+		Env:        fixtureEnv,
+		Force:      true,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+		Generators: []runner.Generator{{Name: config.GeneratorDiff, Generate: GenerateDiff}},
+	})
+	if err != nil {
+		t.Fatalf("Run(...): %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "apis", "v1", "zz_generated.diff.go")); err != nil {
+		t.Fatalf("Run(...): diff not written: %v", err)
+	}
+
+	runGo(t, dir, "vet", "./...")
+	runGo(t, dir, "test", "./...")
+}
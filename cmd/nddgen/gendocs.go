@@ -0,0 +1,112 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+	"github.com/netw-device-driver/ndd-tools/internal/docs"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteDocs = "cannot write API reference documentation"
+)
+
+var (
+	docsDir     string
+	docsFormats []string
+)
+
+var gendocsCmd = &cobra.Command{
+	Use:   "generate-docs",
+	Short: "generate ndd API reference documentation.",
+	Long: "generate a reference page per API group version documenting every managed resource, NetworkNode and " +
+		"NetworkNodeUsage, and the types they use, from their doc comments. Packages must declare their API group " +
+		"using the +" + crd.MarkerGroupName + " marker.",
+	Aliases:      []string{"gen-docs"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorDocs, dirFlag: "docs-dir", formatsFlag: "formats", generate: GenerateDocs, typeErrorsOK: true},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gendocsCmd)
+	addLoadFlags(gendocsCmd)
+	gendocsCmd.Flags().StringVarP(&docsDir, "docs-dir", "", "docs/api", "The directory, relative to the module root, to which reference pages are written.")
+	gendocsCmd.Flags().StringSliceVarP(&docsFormats, "formats", "", []string{docs.FormatMarkdown}, "The formats of reference pages to write; "+docs.FormatMarkdown+" and/or "+docs.FormatHTML+".")
+	addOutputFlags(gendocsCmd, "reference pages")
+}
+
+// GenerateDocs generates a reference page documenting the kinds of the
+// supplied package in each format of the supplied config.Generator. Pages are
+// written to the directory of the config.Generator, named after the package's
+// API group and version.
+func GenerateDocs(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	var kinds []types.Object
+	for _, n := range p.Types.Scope().Names() {
+		o, ok := p.Types.Scope().Lookup(n).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		for _, k := range crdKinds {
+			if k.matches()(o) {
+				kinds = append(kinds, o)
+				break
+			}
+		}
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	b, err := docs.NewBuilder(p.Package)
+	if err != nil {
+		return errors.Wrap(err, errWriteDocs)
+	}
+	page := b.Build(kinds...)
+
+	dir := g.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.Root(), dir)
+	}
+	for _, f := range g.Formats {
+		ext, ok := docs.Extensions[f]
+		if !ok {
+			return errors.Wrap(errors.Errorf("unknown format %q", f), errWriteDocs)
+		}
+		data, err := docs.Render(page, f)
+		if err != nil {
+			return errors.Wrap(err, errWriteDocs)
+		}
+		file := filepath.Join(dir, page.Group+"_"+page.Version+ext)
+		if err := generate.WriteData(file, data, wo...); err != nil {
+			return errors.Wrap(err, errWriteDocs)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteFakes       = "cannot write fake stores"
+	errAbsoluteFakesDir = "fakes directory %s is not relative to the package"
+	errFakesPackageName = "fakes directory %s is not named after a valid Go package name"
+)
+
+// Methods of managed resources used by fake stores.
+const (
+	methodDeepCopy               = "DeepCopy"
+	methodComputeResourceIndexes = "ComputeResourceIndexes"
+)
+
+var (
+	filenameFakes string
+	fakesDir      string
+)
+
+// fakesGenerator is the generator run by generate-fakes. How stores key
+// resources depends on the methods generate-references and generate-deepcopy
+// write, so their files are inputs of it.
+var fakesGenerator = namedGenerator{
+	name:     config.GeneratorFakes,
+	flag:     "filename-fakes",
+	dirFlag:  "fakes-dir",
+	generate: GenerateFakes,
+	inputs:   []string{config.GeneratorReferences, config.GeneratorDeepCopy},
+}
+
+var genfakesCmd = &cobra.Command{
+	Use:   "generate-fakes",
+	Short: "generate ndd fake stores for tests.",
+	Long: "generate an in-memory store of every managed resource, for use by fake external clients in tests, in a " +
+		"subpackage of its package. Stores create, get, update, delete and list copies of resources, keyed by their " +
+		"resource indexes if they have a " + methodComputeResourceIndexes + " method (see generate-references) or by " +
+		"their name otherwise, record the calls made to them, and return injected errors.",
+	Aliases:      []string{"gen-fakes"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{fakesGenerator})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genfakesCmd)
+	addLoadFlags(genfakesCmd)
+	genfakesCmd.Flags().StringVarP(&filenameFakes, "filename-fakes", "", "zz_generated.fake.go", "The filename of generated fake store files.")
+	genfakesCmd.Flags().StringVarP(&fakesDir, "fakes-dir", "", "fake", "The subpackage, relative to each package, to which fake stores are written.")
+	addOutputFlags(genfakesCmd, "fake stores")
+}
+
+// GenerateFakes generates a fake store of every managed resource of the
+// supplied package. They are written to the subpackage of the supplied
+// package named by the directory of the supplied config.Generator, which must
+// be relative, so that the stores of each package are written to a distinct
+// subpackage. The last element of the directory is the name of the subpackage.
+func GenerateFakes(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	if filepath.IsAbs(g.Dir) {
+		return errors.Wrap(errors.Errorf(errAbsoluteFakesDir, g.Dir), errWriteFakes)
+	}
+	name := path.Base(filepath.ToSlash(g.Dir))
+	if !token.IsIdentifier(name) || name == "_" {
+		return errors.Wrap(errors.Errorf(errFakesPackageName, g.Dir), errWriteFakes)
+	}
+	dir := filepath.Join(filepath.Dir(p.GoFiles[0]), g.Dir)
+
+	var kinds []types.Object
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !p.Managed()(o) {
+			continue
+		}
+		if !hasMethod(o, methodDeepCopy) {
+			errs = append(errs, errors.Errorf("managed resource %s has no %s method", o.Name(), methodDeepCopy).Error())
+			continue
+		}
+		kinds = append(kinds, o)
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteFakes)
+	}
+
+	err := generate.WriteCode(p.Package, filepath.Join(dir, g.Filename), func(f *jen.File) {
+		if len(kinds) == 0 {
+			return
+		}
+		WriteFakeCommon(f)
+		for _, o := range kinds {
+			WriteFakeStore(f, p.PkgPath, o)
+		}
+	}, append([]generate.WriteOption{
+		generate.WithHeaders(header),
+		generate.WithPackageName(name),
+	}, wo...)...)
+
+	return errors.Wrap(err, errWriteFakes)
+}
+
+// WriteFakeCommon writes the declarations shared by the fake stores of a
+// package to the supplied file.
+func WriteFakeCommon(f *jen.File) {
+	f.Comment("Errors returned by the fake stores of this package.")
+	f.Var().Defs(
+		jen.Comment("ErrNotFound is returned for resources that are not in a store."),
+		jen.Id("ErrNotFound").Op("=").Qual("errors", "New").Call(jen.Lit("resource not found")),
+		jen.Line(),
+		jen.Comment("ErrAlreadyExists is returned when creating a resource that is already in a store."),
+		jen.Id("ErrAlreadyExists").Op("=").Qual("errors", "New").Call(jen.Lit("resource already exists")),
+	)
+
+	f.Comment("A Call is a call to a method of a fake store.")
+	f.Type().Id("Call").Struct(
+		jen.Comment("Method that was called, e.g. Create."),
+		jen.Id("Method").String(),
+		jen.Line(),
+		jen.Comment("Key of the resource the method was called for, if any."),
+		jen.Id("Key").String(),
+	)
+
+	f.Comment("indexKey returns the supplied resource indexes as a key sorted by index, e.g. id=1,name=a.")
+	f.Func().Id("indexKey").Params(jen.Id("idx").Map(jen.String()).String()).String().Block(
+		jen.Id("keys").Op(":=").Make(jen.Index().String(), jen.Lit(0), jen.Len(jen.Id("idx"))),
+		jen.For(jen.Id("k").Op(":=").Range().Id("idx")).Block(
+			jen.Id("keys").Op("=").Append(jen.Id("keys"), jen.Id("k")),
+		),
+		jen.Qual("sort", "Strings").Call(jen.Id("keys")),
+		jen.For(jen.List(jen.Id("i"), jen.Id("k")).Op(":=").Range().Id("keys")).Block(
+			jen.Id("keys").Index(jen.Id("i")).Op("=").Id("k").Op("+").Lit("=").Op("+").Id("idx").Index(jen.Id("k")),
+		),
+		jen.Return(jen.Qual("strings", "Join").Call(jen.Id("keys"), jen.Lit(","))),
+	)
+}
+
+// WriteFakeStore writes a fake store of the supplied managed resource, which
+// is declared by the package with the supplied import path, to the supplied
+// file.
+func WriteFakeStore(f *jen.File, pkgPath string, o types.Object) {
+	kind := o.Name()
+	store := kind + "Store"
+	keyFn := kind + "Key"
+	mg := func() *jen.Statement { return jen.Op("*").Qual(pkgPath, kind) }
+	lock := func(g *jen.Group) {
+		g.Id("s").Dot("mu").Dot("Lock").Call()
+		g.Defer().Id("s").Dot("mu").Dot("Unlock").Call()
+	}
+	errorf := func(sentinel string) jen.Code {
+		return jen.Qual("fmt", "Errorf").Call(jen.Lit("%s %s: %w"), jen.Lit(kind), jen.Id("key"), jen.Id(sentinel))
+	}
+	ctx := jen.Id("_").Qual("context", "Context")
+
+	if hasMethod(o, methodComputeResourceIndexes) {
+		f.Commentf("%s returns the key of the supplied %s in a %s: its resource indexes, or its name if it has none.", keyFn, kind, store)
+		f.Func().Id(keyFn).Params(jen.Id("mg").Add(mg())).String().Block(
+			jen.If(jen.Id("idx").Op(":=").Id("mg").Dot(methodComputeResourceIndexes).Call(), jen.Len(jen.Id("idx")).Op(">").Lit(0)).Block(
+				jen.Return(jen.Id("indexKey").Call(jen.Id("idx"))),
+			),
+			jen.Return(jen.Id("mg").Dot("GetName").Call()),
+		)
+	} else {
+		f.Commentf("%s returns the key of the supplied %s in a %s: its name.", keyFn, kind, store)
+		f.Func().Id(keyFn).Params(jen.Id("mg").Add(mg())).String().Block(
+			jen.Return(jen.Id("mg").Dot("GetName").Call()),
+		)
+	}
+
+	f.Commentf("A %s is an in-memory store of %ss, for use by fake external clients in", store, kind)
+	f.Commentf("tests. It stores copies of %ss by their %s, and is safe for concurrent use.", kind, keyFn)
+	f.Type().Id(store).Struct(
+		jen.Comment("Errors returned by the methods of the store instead of calling them, by"),
+		jen.Comment("method name, e.g. Create."),
+		jen.Id("Errors").Map(jen.String()).Error(),
+		jen.Line(),
+		jen.Id("mu").Qual("sync", "Mutex"),
+		jen.Id("objects").Map(jen.String()).Add(mg()),
+		jen.Id("calls").Index().Id("Call"),
+	)
+
+	f.Commentf("New%s returns a %s containing the supplied %ss.", store, store, kind)
+	f.Func().Id("New"+store).Params(jen.Id("objs").Op("...").Add(mg())).Op("*").Id(store).Block(
+		jen.Id("s").Op(":=").Op("&").Id(store).Values(jen.Dict{jen.Id("objects"): jen.Map(jen.String()).Add(mg()).Values()}),
+		jen.For(jen.List(jen.Id("_"), jen.Id("o")).Op(":=").Range().Id("objs")).Block(
+			jen.Id("s").Dot("objects").Index(jen.Id(keyFn).Call(jen.Id("o"))).Op("=").Id("o").Dot(methodDeepCopy).Call(),
+		),
+		jen.Return(jen.Id("s")),
+	)
+
+	f.Comment("Calls returns the calls made to the store, in order.")
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("Calls").Params().Index().Id("Call").BlockFunc(func(g *jen.Group) {
+		lock(g)
+		g.Return(jen.Append(jen.Index().Id("Call").Parens(jen.Nil()), jen.Id("s").Dot("calls").Op("...")))
+	})
+
+	f.Comment("record a call to the supplied method, returning the error injected for it, if any.")
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("record").Params(jen.List(jen.Id("method"), jen.Id("key")).String()).Error().Block(
+		jen.Id("s").Dot("calls").Op("=").Append(jen.Id("s").Dot("calls"), jen.Id("Call").Values(jen.Dict{jen.Id("Method"): jen.Id("method"), jen.Id("Key"): jen.Id("key")})),
+		jen.Return(jen.Id("s").Dot("Errors").Index(jen.Id("method"))),
+	)
+
+	f.Commentf("Create a copy of the supplied %s. It returns ErrAlreadyExists if the store", kind)
+	f.Commentf("contains a %s with the same key.", kind)
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("Create").Params(ctx.Clone(), jen.Id("mg").Add(mg())).Error().BlockFunc(func(g *jen.Group) {
+		lock(g)
+		g.Id("key").Op(":=").Id(keyFn).Call(jen.Id("mg"))
+		g.If(jen.Err().Op(":=").Id("s").Dot("record").Call(jen.Lit("Create"), jen.Id("key")), jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+		g.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Id("s").Dot("objects").Index(jen.Id("key")), jen.Id("ok")).Block(jen.Return(errorf("ErrAlreadyExists")))
+		g.Id("s").Dot("objects").Index(jen.Id("key")).Op("=").Id("mg").Dot(methodDeepCopy).Call()
+		g.Return(jen.Nil())
+	})
+
+	f.Commentf("Get a copy of the %s with the supplied key. It returns ErrNotFound if the", kind)
+	f.Commentf("store contains no such %s.", kind)
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("Get").Params(ctx.Clone(), jen.Id("key").String()).Params(mg(), jen.Error()).BlockFunc(func(g *jen.Group) {
+		lock(g)
+		g.If(jen.Err().Op(":=").Id("s").Dot("record").Call(jen.Lit("Get"), jen.Id("key")), jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+		g.List(jen.Id("o"), jen.Id("ok")).Op(":=").Id("s").Dot("objects").Index(jen.Id("key"))
+		g.If(jen.Op("!").Id("ok")).Block(jen.Return(jen.Nil(), errorf("ErrNotFound")))
+		g.Return(jen.Id("o").Dot(methodDeepCopy).Call(), jen.Nil())
+	})
+
+	f.Commentf("Update the stored %s with a copy of the supplied %s. It returns ErrNotFound if", kind, kind)
+	f.Commentf("the store contains no %s with the same key.", kind)
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("Update").Params(ctx.Clone(), jen.Id("mg").Add(mg())).Error().BlockFunc(func(g *jen.Group) {
+		lock(g)
+		g.Id("key").Op(":=").Id(keyFn).Call(jen.Id("mg"))
+		g.If(jen.Err().Op(":=").Id("s").Dot("record").Call(jen.Lit("Update"), jen.Id("key")), jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+		g.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Id("s").Dot("objects").Index(jen.Id("key")), jen.Op("!").Id("ok")).Block(jen.Return(errorf("ErrNotFound")))
+		g.Id("s").Dot("objects").Index(jen.Id("key")).Op("=").Id("mg").Dot(methodDeepCopy).Call()
+		g.Return(jen.Nil())
+	})
+
+	f.Commentf("Delete the %s with the supplied key. It returns ErrNotFound if the store", kind)
+	f.Commentf("contains no such %s.", kind)
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("Delete").Params(ctx.Clone(), jen.Id("key").String()).Error().BlockFunc(func(g *jen.Group) {
+		lock(g)
+		g.If(jen.Err().Op(":=").Id("s").Dot("record").Call(jen.Lit("Delete"), jen.Id("key")), jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err()))
+		g.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Id("s").Dot("objects").Index(jen.Id("key")), jen.Op("!").Id("ok")).Block(jen.Return(errorf("ErrNotFound")))
+		g.Delete(jen.Id("s").Dot("objects"), jen.Id("key"))
+		g.Return(jen.Nil())
+	})
+
+	f.Commentf("List copies of the stored %ss, sorted by key.", kind)
+	f.Func().Params(jen.Id("s").Op("*").Id(store)).Id("List").Params(ctx.Clone()).Params(jen.Index().Add(mg()), jen.Error()).BlockFunc(func(g *jen.Group) {
+		lock(g)
+		g.If(jen.Err().Op(":=").Id("s").Dot("record").Call(jen.Lit("List"), jen.Lit("")), jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err()))
+		g.Id("keys").Op(":=").Make(jen.Index().String(), jen.Lit(0), jen.Len(jen.Id("s").Dot("objects")))
+		g.For(jen.Id("k").Op(":=").Range().Id("s").Dot("objects")).Block(
+			jen.Id("keys").Op("=").Append(jen.Id("keys"), jen.Id("k")),
+		)
+		g.Qual("sort", "Strings").Call(jen.Id("keys"))
+		g.Id("l").Op(":=").Make(jen.Index().Add(mg()), jen.Lit(0), jen.Len(jen.Id("keys")))
+		g.For(jen.List(jen.Id("_"), jen.Id("k")).Op(":=").Range().Id("keys")).Block(
+			jen.Id("l").Op("=").Append(jen.Id("l"), jen.Id("s").Dot("objects").Index(jen.Id("k")).Dot(methodDeepCopy).Call()),
+		)
+		g.Return(jen.Id("l"), jen.Nil())
+	})
+}
+
+// hasMethod returns true if a pointer to the supplied Object has a method with
+// the supplied name.
+func hasMethod(o types.Object, name string) bool {
+	return types.NewMethodSet(types.NewPointer(o.Type())).Lookup(o.Pkg(), name) != nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+func TestGenerateFakesDir(t *testing.T) {
+	abs, _ := filepath.Abs("fake")
+	for _, dir := range []string{"fake-stores", "1fake", "testing/type", "_", ".", abs} {
+		g := config.Generator{Filename: "zz_generated.fake.go", Dir: dir}
+		if err := GenerateFakes(g, config.Imports{}, "", &runner.Package{}); err == nil {
+			t.Errorf("GenerateFakes(...): want error for directory %q, got none", dir)
+		}
+	}
+}
+
+// TestGenerateFakesCache tests that fake stores are regenerated when the
+// methods they depend on are generated after them.
+func TestGenerateFakesCache(t *testing.T) {
+	dir := writeFixture(t, 1)
+	deepCopy := "package v1\n\nfunc (in *Thing) DeepCopy() *Thing {\n\tout := *in\n\treturn &out\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "apis", "v1", "deepcopy.go"), []byte(deepCopy), 0644); err != nil { // nolint:gosec
+		t.Fatal(err)
+	}
+	run := func(name string, ng namedGenerator) {
+		t.Helper()
+		rc := runner.Config{
+			Name:       name,
+			Paths:      []string{"./apis/v1"},
+			Dir:        dir,
+			Env:        fixtureEnv,
+			Stdout:     io.Discard,
+			Stderr:     io.Discard,
+			Generators: []runner.Generator{ng.generator()},
+		}
+		if err := runner.Run(rc); err != nil {
+			t.Fatalf("Run(%s): %v", name, err)
+		}
+	}
+	keyedByIndexes := func() bool {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, "apis", "v1", "fake", "zz_generated.fake.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Contains(string(data), methodComputeResourceIndexes)
+	}
+
+	run("generate-fakes", fakesGenerator)
+	if keyedByIndexes() {
+		t.Fatalf("generate-fakes: want stores keyed by name before generate-references")
+	}
+	run("generate-references", namedGenerator{name: config.GeneratorReferences, generate: GenerateReferences})
+	run("generate-fakes", fakesGenerator)
+	if !keyedByIndexes() {
+		t.Errorf("generate-fakes: want stores keyed by resource indexes after generate-references")
+	}
+}
@@ -17,61 +17,59 @@ limitations under the License.
 package nddgen
 
 import (
-	"fmt"
-	"io/ioutil"
 	"path/filepath"
+	"strings"
 
-	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/dave/jennifer/jen"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
 	"github.com/netw-device-driver/ndd-tools/internal/generate"
 	"github.com/netw-device-driver/ndd-tools/internal/match"
 	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"golang.org/x/tools/go/packages"
 )
 
 const (
-	// LoadMode used to load all packages.
-	LoadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax
-
-	// DisableMarker used to disable generation of managed resource methods for
-	// a type that otherwise appears to be a managed resource that is missing a
-	// subset of its methods.
-	DisableMarker = "ndd:generate:methods"
-)
-const (
-	CoreAlias  = "corev1"
-	CoreImport = "k8s.io/api/core/v1"
-
-	RuntimeAlias  = "nddv1"
-	RuntimeImport = "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
-
-	ResourceAlias  = "resource"
-	ResourceImport = "github.com/netw-device-driver/ndd-runtime/pkg/resource"
-)
-
-const (
-	errLoadPackages                    = "cannot load packages"
-	errReadheaderFile                  = "cannot read header file"
 	errWriteManagedResourceMethod      = "cannot write managed resource method set for package"
 	errWriteManagedResourceListMethod  = "cannot write managed resource list method set for package"
-	errLoadingPackages                 = "error loading packages using pattern"
 	errWriteNetworkNodeMethod          = "cannot write network node methods"
 	errWriteNetworkNodeUsageMethod     = "cannot write network node usage methods"
 	errWriteNetworkNodeUsageListMethod = "cannot write network node usage list methods"
+	errWriteTests                      = "cannot write method tests"
 )
 
 var (
-	headerFile          string
 	filenameManaged     string
 	filenameManagedList string
 	filenameNN          string
 	filenameNNU         string
 	filenameNNUList     string
-	pattern             string
+	withTests           bool
 )
 
-// startCmd represents the start command for the network device driver
+// methodSets are the generators run by generate-methodsets, in order, keyed by
+// their config.Generator name.
+var methodSets = []struct {
+	name      string
+	flag      string
+	generate  runner.GeneratorFunc
+	methods   func(receiver string, i config.Imports) method.Set
+	tests     func(receiver string) method.TestSet
+	matches   func() match.Object
+	generated func(p *runner.Package) match.Object
+}{
+	{name: config.GeneratorManaged, flag: "filename-managed", generate: GenerateManaged, methods: ManagedMethods, tests: ManagedTests, matches: match.Managed, generated: (*runner.Package).Managed},
+	{name: config.GeneratorManagedList, flag: "filename-managed-list", generate: GenerateManagedList, methods: ManagedListMethods, tests: ListTests, matches: match.ManagedList, generated: (*runner.Package).ManagedList},
+	{name: config.GeneratorNetworkNode, flag: "filename-nn", generate: GenerateNetworkNode, methods: NetworkNodeMethods, tests: NetworkNodeTests, matches: match.NetworkNode, generated: (*runner.Package).NetworkNode},
+	{name: config.GeneratorNetworkNodeUsage, flag: "filename-nnu", generate: GenerateNetworkNodeUsage, methods: NetworkNodeUsageMethods, tests: NetworkNodeUsageTests, matches: match.NetworkNodeUsage, generated: (*runner.Package).NetworkNodeUsage},
+	{name: config.GeneratorNetworkNodeUsageList, flag: "filename-nnu-list", generate: GenerateNetworkNodeUsageList, methods: NetworkNodeUsageListMethods, tests: ListTests, matches: match.NetworkNodeUsageList, generated: (*runner.Package).NetworkNodeUsageList},
+}
+
+// genmethodsetCmd represents the generate-methodsets command, which writes the
+// method sets of the managed resource, network node and network node usage
+// types, and of their lists.
 var genmethodsetCmd = &cobra.Command{
 	Use:          "generate-methodsets",
 	Short:        "generate a ndd method sets.",
@@ -79,156 +77,125 @@ var genmethodsetCmd = &cobra.Command{
 	Aliases:      []string{"gen-methodsets"},
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Println("ndd-gen started ...")
-		pkgs, err := packages.Load(&packages.Config{Mode: LoadMode}, pattern)
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("%s : %s", errLoadPackages, pattern))
+		gens := make([]namedGenerator, 0, len(methodSets))
+		for _, ms := range methodSets {
+			gens = append(gens, namedGenerator{
+				name:      ms.name,
+				flag:      ms.flag,
+				testsFlag: "with-tests",
+				generate:  WithTests(ms.generate, ms.tests, ms.generated),
+			})
 		}
-
-		header := ""
-		if headerFile != "" {
-			h, err := ioutil.ReadFile(headerFile)
-			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", errReadheaderFile, headerFile))
-			}
-			header = string(h)
-		}
-
-		for _, pkg := range pkgs {
-			for _, err := range pkg.Errors {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", errLoadingPackages, pattern))
-			}
-			if err := GenerateManaged(filenameManaged, header, pkg); err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", err, pkg.PkgPath))
-			}
-			if err := GenerateManagedList(filenameManagedList, header, pkg); err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", err, pkg.PkgPath))
-			}
-			if err := GenerateNetworkNode(filenameNN, header, pkg); err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", err, pkg.PkgPath))
-			}
-			if err := GenerateNetworkNodeUsage(filenameNNU, header, pkg); err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", err, pkg.PkgPath))
-			}
-			if err := GenerateNetworkNodeUsageList(filenameNNUList, header, pkg); err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s : %s", err, pkg.PkgPath))
-			}
-		}
-		fmt.Println("ndd-gen finished ...")
-		return nil
+		return runGenerators(cmd, gens)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(genmethodsetCmd)
-	genmethodsetCmd.Flags().StringVarP(&headerFile, "header-file", "", "", "The contents of this file will be added to the top of all generated files.")
+	addLoadFlags(genmethodsetCmd)
 	genmethodsetCmd.Flags().StringVarP(&filenameManaged, "filename-managed", "", "zz_generated.managed.go", "The filename of generated managed resource files.")
 	genmethodsetCmd.Flags().StringVarP(&filenameManagedList, "filename-managed-list", "", "zz_generated.managedlist.go", "The filename of generated managed list resource files.")
 	genmethodsetCmd.Flags().StringVarP(&filenameNN, "filename-nn", "", "zz_generated.nn.go", "The filename of generated NetworkNode files.")
 	genmethodsetCmd.Flags().StringVarP(&filenameNNU, "filename-nnu", "", "zz_generated.nnu.go", "The filename of generated NetworkNode usage files.")
 	genmethodsetCmd.Flags().StringVarP(&filenameNNUList, "filename-nnu-list", "", "zz_generated.nnulist.go", "The filename of generated NetworkNode list usage files.")
-	genmethodsetCmd.Flags().StringVarP(&pattern, "paths", "", "", "Package(s) for which to generate methods, for example github.com/netw-device-driver/ndd-core/apis/...")
+	genmethodsetCmd.Flags().BoolVarP(&withTests, "with-tests", "", false, "Also write a companion _test.go file per method set file that tests the generated methods.")
+	addOutputFlags(genmethodsetCmd, "method sets")
 }
 
-// GenerateManaged generates the resource.Managed method set.
-func GenerateManaged(filename, header string, p *packages.Package) error {
-	receiver := "mg"
-
-	methods := method.Set{
-		"SetActive":               method.NewSetActive(receiver, RuntimeImport),
-		"GetActive":               method.NewGetActive(receiver, RuntimeImport),
-		"SetConditions":           method.NewSetConditions(receiver, RuntimeImport),
-		"GetCondition":            method.NewGetCondition(receiver, RuntimeImport),
-		"GetNetworkNodeReference": method.NewGetNetworkNodeReference(receiver, RuntimeImport),
-		"SetNetworkNodeReference": method.NewSetNetworkNodeReference(receiver, RuntimeImport),
-		"SetDeletionPolicy":       method.NewSetDeletionPolicy(receiver, RuntimeImport),
-		"GetDeletionPolicy":       method.NewGetDeletionPolicy(receiver, RuntimeImport),
-		"GetTarget":               method.NewGetTarget(receiver, RuntimeImport),
-		"SetTarget":               method.NewSetTarget(receiver, RuntimeImport),
-		"GetExternalLeafRefs":     method.NewGetExternalLeafRefs(receiver, RuntimeImport),
-		"SetExternalLeafRefs":     method.NewSetExternalLeafRefs(receiver, RuntimeImport),
-		"GetResourceIndexes":      method.NewGetResourceIndexes(receiver, RuntimeImport),
-		"SetResourceIndexes":      method.NewSetResourceIndexes(receiver, RuntimeImport),
+// WithTests returns a runner.GeneratorFunc that runs the supplied generator, then
+// writes the supplied tests of the methods it generated for the objects of the
+// package that are matched by the matcher the supplied function returns for
+// it. Tests are written to a companion _test.go file of the generator's file
+// if its config.Generator enables them; a previously generated test file is
+// removed otherwise.
+func WithTests(gen runner.GeneratorFunc, tests func(receiver string) method.TestSet, generated func(p *runner.Package) match.Object) runner.GeneratorFunc {
+	return func(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+		if err := gen(g, i, header, p, wo...); err != nil {
+			return err
+		}
+
+		file := filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename)
+		ts := tests(g.Receiver)
+		matches := generated(p)
+		err := generate.WriteCode(p.Package, strings.TrimSuffix(file, ".go")+"_test.go", func(f *jen.File) {
+			if !g.WithTests() {
+				return
+			}
+			for _, n := range p.Types.Scope().Names() {
+				o := p.Types.Scope().Lookup(n)
+				if matches(o) {
+					ts.Write(f, o, method.DefinedOutside(p.Fset, file))
+				}
+			}
+		}, append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{i.Runtime.Path: i.Runtime.Alias}),
+		}, wo...)...)
+
+		return errors.Wrap(err, errWriteTests)
 	}
+}
 
-	err := generate.WriteMethods(p, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), filename),
-		generate.WithHeaders(header),
-		generate.WithImportAliases(map[string]string{
-			CoreImport:    CoreAlias,
-			RuntimeImport: RuntimeAlias,
-		}),
-		generate.WithMatcher(match.AllOf(
-			match.Managed(),
-			match.DoesNotHaveMarker(comments.In(p), DisableMarker, "false")),
-		),
+// GenerateManaged generates the resource.Managed method set.
+func GenerateManaged(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	methods := ManagedMethods(g.Receiver, i)
+
+	err := generate.WriteMethods(p.Package, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{
+				i.Core.Path:    i.Core.Alias,
+				i.Runtime.Path: i.Runtime.Alias,
+			}),
+			generate.WithMatcher(p.Managed()),
+		}, wo...)...,
 	)
 
 	return errors.Wrap(err, errWriteManagedResourceMethod)
 }
 
 // GenerateManagedList generates the resource.ManagedList method set.
-func GenerateManagedList(filename, header string, p *packages.Package) error {
-	receiver := "l"
-
-	methods := method.Set{
-		"GetItems": method.NewManagedGetItems(receiver, ResourceImport),
-	}
-
-	err := generate.WriteMethods(p, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), filename),
-		generate.WithHeaders(header),
-		generate.WithImportAliases(map[string]string{
-			ResourceImport: ResourceAlias,
-		}),
-		generate.WithMatcher(match.AllOf(
-			match.ManagedList(),
-			match.DoesNotHaveMarker(comments.In(p), DisableMarker, "false")),
-		),
+func GenerateManagedList(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	methods := ManagedListMethods(g.Receiver, i)
+
+	err := generate.WriteMethods(p.Package, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{
+				i.Resource.Path: i.Resource.Alias,
+			}),
+			generate.WithMatcher(p.ManagedList()),
+		}, wo...)...,
 	)
 
 	return errors.Wrap(err, errWriteManagedResourceListMethod)
 }
 
 // GenerateNetworkNode generates the resource.NetworkNode method set.
-func GenerateNetworkNode(filename, header string, p *packages.Package) error {
-	receiver := "p"
-
-	methods := method.Set{
-		"SetUsers":      method.NewSetUsers(receiver),
-		"GetUsers":      method.NewGetUsers(receiver),
-		"SetConditions": method.NewSetConditions(receiver, RuntimeImport),
-		"GetCondition":  method.NewGetCondition(receiver, RuntimeImport),
-	}
-
-	err := generate.WriteMethods(p, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), filename),
-		generate.WithHeaders(header),
-		generate.WithImportAliases(map[string]string{RuntimeImport: RuntimeAlias}),
-		generate.WithMatcher(match.AllOf(
-			match.NetworkNode(),
-			match.DoesNotHaveMarker(comments.In(p), DisableMarker, "false")),
-		),
+func GenerateNetworkNode(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	methods := NetworkNodeMethods(g.Receiver, i)
+
+	err := generate.WriteMethods(p.Package, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{i.Runtime.Path: i.Runtime.Alias}),
+			generate.WithMatcher(p.NetworkNode()),
+		}, wo...)...,
 	)
 
 	return errors.Wrap(err, errWriteNetworkNodeMethod)
 }
 
 // GenerateNetworkNodeUsage generates the resource.NetworkNodeUsage method set.
-func GenerateNetworkNodeUsage(filename, header string, p *packages.Package) error {
-	receiver := "p"
-
-	methods := method.Set{
-		"SetNetworkNodeReference": method.NewSetRootNetworkNodeReference(receiver, RuntimeImport),
-		"GetNetworkNodeReference": method.NewGetRootNetworkNodeReference(receiver, RuntimeImport),
-		"SetResourceReference":    method.NewSetRootResourceReference(receiver, RuntimeImport),
-		"GetResourceReference":    method.NewGetRootResourceReference(receiver, RuntimeImport),
-	}
-
-	err := generate.WriteMethods(p, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), filename),
-		generate.WithHeaders(header),
-		generate.WithImportAliases(map[string]string{RuntimeImport: RuntimeAlias}),
-		generate.WithMatcher(match.AllOf(
-			match.NetworkNodeUsage(),
-			match.DoesNotHaveMarker(comments.In(p), DisableMarker, "false")),
-		),
+func GenerateNetworkNodeUsage(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	methods := NetworkNodeUsageMethods(g.Receiver, i)
+
+	err := generate.WriteMethods(p.Package, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{i.Runtime.Path: i.Runtime.Alias}),
+			generate.WithMatcher(p.NetworkNodeUsage()),
+		}, wo...)...,
 	)
 
 	return errors.Wrap(err, errWriteNetworkNodeUsageMethod)
@@ -236,21 +203,108 @@ func GenerateNetworkNodeUsage(filename, header string, p *packages.Package) erro
 
 // GenerateNetworkNodeUsageList generates the
 // resource.NetworkNodeUsageList method set.
-func GenerateNetworkNodeUsageList(filename, header string, p *packages.Package) error {
-	receiver := "p"
+func GenerateNetworkNodeUsageList(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	methods := NetworkNodeUsageListMethods(g.Receiver, i)
+
+	err := generate.WriteMethods(p.Package, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithImportAliases(map[string]string{i.Runtime.Path: i.Runtime.Alias}),
+			generate.WithMatcher(p.NetworkNodeUsageList()),
+		}, wo...)...,
+	)
 
-	methods := method.Set{
-		"GetItems": method.NewNetworkNodeUsageGetItems(receiver, ResourceImport),
+	return errors.Wrap(err, errWriteNetworkNodeUsageListMethod)
+}
+
+// ManagedMethods returns the resource.Managed method set.
+func ManagedMethods(receiver string, i config.Imports) method.Set {
+	return method.Set{
+		"SetActive":               method.NewSetActive(receiver, i.Runtime.Path),
+		"GetActive":               method.NewGetActive(receiver, i.Runtime.Path),
+		"SetConditions":           method.NewSetConditions(receiver, i.Runtime.Path),
+		"GetCondition":            method.NewGetCondition(receiver, i.Runtime.Path),
+		"GetNetworkNodeReference": method.NewGetNetworkNodeReference(receiver, i.Runtime.Path),
+		"SetNetworkNodeReference": method.NewSetNetworkNodeReference(receiver, i.Runtime.Path),
+		"SetDeletionPolicy":       method.NewSetDeletionPolicy(receiver, i.Runtime.Path),
+		"GetDeletionPolicy":       method.NewGetDeletionPolicy(receiver, i.Runtime.Path),
+		"GetTarget":               method.NewGetTarget(receiver, i.Runtime.Path),
+		"SetTarget":               method.NewSetTarget(receiver, i.Runtime.Path),
+		"GetExternalLeafRefs":     method.NewGetExternalLeafRefs(receiver, i.Runtime.Path),
+		"SetExternalLeafRefs":     method.NewSetExternalLeafRefs(receiver, i.Runtime.Path),
+		"GetResourceIndexes":      method.NewGetResourceIndexes(receiver, i.Runtime.Path),
+		"SetResourceIndexes":      method.NewSetResourceIndexes(receiver, i.Runtime.Path),
 	}
+}
 
-	err := generate.WriteMethods(p, methods, filepath.Join(filepath.Dir(p.GoFiles[0]), filename),
-		generate.WithHeaders(header),
-		generate.WithImportAliases(map[string]string{RuntimeImport: RuntimeAlias}),
-		generate.WithMatcher(match.AllOf(
-			match.NetworkNodeUsageList(),
-			match.DoesNotHaveMarker(comments.In(p), DisableMarker, "false")),
-		),
-	)
+// ManagedListMethods returns the resource.ManagedList method set.
+func ManagedListMethods(receiver string, i config.Imports) method.Set {
+	return method.Set{
+		"GetItems": method.NewManagedGetItems(receiver, i.Resource.Path),
+	}
+}
 
-	return errors.Wrap(err, errWriteNetworkNodeUsageListMethod)
+// NetworkNodeMethods returns the resource.NetworkNode method set.
+func NetworkNodeMethods(receiver string, i config.Imports) method.Set {
+	return method.Set{
+		"SetUsers":      method.NewSetUsers(receiver),
+		"GetUsers":      method.NewGetUsers(receiver),
+		"SetConditions": method.NewSetConditions(receiver, i.Runtime.Path),
+		"GetCondition":  method.NewGetCondition(receiver, i.Runtime.Path),
+	}
+}
+
+// NetworkNodeUsageMethods returns the resource.NetworkNodeUsage method set.
+func NetworkNodeUsageMethods(receiver string, i config.Imports) method.Set {
+	return method.Set{
+		"SetNetworkNodeReference": method.NewSetRootNetworkNodeReference(receiver, i.Runtime.Path),
+		"GetNetworkNodeReference": method.NewGetRootNetworkNodeReference(receiver, i.Runtime.Path),
+		"SetResourceReference":    method.NewSetRootResourceReference(receiver, i.Runtime.Path),
+		"GetResourceReference":    method.NewGetRootResourceReference(receiver, i.Runtime.Path),
+	}
+}
+
+// NetworkNodeUsageListMethods returns the resource.NetworkNodeUsageList method set.
+func NetworkNodeUsageListMethods(receiver string, i config.Imports) method.Set {
+	return method.Set{
+		"GetItems": method.NewNetworkNodeUsageGetItems(receiver, i.Resource.Path),
+	}
+}
+
+// ManagedTests returns the tests of the resource.Managed method set.
+func ManagedTests(receiver string) method.TestSet {
+	return method.TestSet{
+		"Active":               method.NewAccessorTest(receiver, "SetActive", "GetActive", fields.NameSpec, "Active"),
+		"Conditions":           method.NewConditionsTest(receiver),
+		"NetworkNodeReference": method.NewAccessorTest(receiver, "SetNetworkNodeReference", "GetNetworkNodeReference", fields.NameSpec, "NetworkNodeReference"),
+		"DeletionPolicy":       method.NewAccessorTest(receiver, "SetDeletionPolicy", "GetDeletionPolicy", fields.NameSpec, "DeletionPolicy"),
+		"Target":               method.NewAccessorTest(receiver, "SetTarget", "GetTarget", fields.NameStatus, "Target"),
+		"ExternalLeafRefs":     method.NewAccessorTest(receiver, "SetExternalLeafRefs", "GetExternalLeafRefs", fields.NameStatus, "ExternalLeafRefs"),
+		"ResourceIndexes":      method.NewAccessorTest(receiver, "SetResourceIndexes", "GetResourceIndexes", fields.NameStatus, "ResourceIndexes"),
+	}
+}
+
+// ListTests returns the tests of the resource.ManagedList and
+// resource.NetworkNodeUsageList method sets.
+func ListTests(receiver string) method.TestSet {
+	return method.TestSet{
+		"GetItems": method.NewGetItemsTest(receiver),
+	}
+}
+
+// NetworkNodeTests returns the tests of the resource.NetworkNode method set.
+func NetworkNodeTests(receiver string) method.TestSet {
+	return method.TestSet{
+		"Users":      method.NewAccessorTest(receiver, "SetUsers", "GetUsers", fields.NameStatus, "Users"),
+		"Conditions": method.NewConditionsTest(receiver),
+	}
+}
+
+// NetworkNodeUsageTests returns the tests of the resource.NetworkNodeUsage
+// method set.
+func NetworkNodeUsageTests(receiver string) method.TestSet {
+	return method.TestSet{
+		"NetworkNodeReference": method.NewAccessorTest(receiver, "SetNetworkNodeReference", "GetNetworkNodeReference", "NetworkNodeReference"),
+		"ResourceReference":    method.NewAccessorTest(receiver, "SetResourceReference", "GetResourceReference", "ResourceReference"),
+	}
 }
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// TestWithTests generates the method sets of a fixture module along with their
+// tests, then runs the generated tests against the generated methods.
+func TestWithTests(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+
+	dir := writeFixture(t, 1)
+	if err := runner.Run(methodSetsConfig(dir, true, 1)); err != nil {
+		t.Fatalf("Run(...): %v", err)
+	}
+
+	for _, file := range []string{"zz_generated.managed_test.go", "zz_generated.managedlist_test.go"} {
+		if _, err := os.Stat(filepath.Join(dir, "apis", "v1", file)); err != nil {
+			t.Errorf("Run(...): tests not written: %v", err)
+		}
+	}
+
+	runGo(t, dir, "vet", "./...")
+	runGo(t, dir, "test", "./...")
+}
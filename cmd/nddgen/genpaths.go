@@ -0,0 +1,107 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/gnmipath"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWritePaths = "cannot write gNMI path builders"
+)
+
+var filenamePaths string
+
+var genpathsCmd = &cobra.Command{
+	Use:   "generate-paths",
+	Short: "generate ndd gNMI path builders.",
+	Long: "generate a function that builds the gNMI path of every container, list and leaf of the spec of every " +
+		"managed resource, taking the keys of its lists as typed parameters. Paths begin at the spec fields marked " +
+		"with an absolute +" + comments.MarkerYangPath + " marker, e.g. +" + comments.MarkerYangPath + "=/interface, " +
+		"and follow JSON names or relative +" + comments.MarkerYangPath + " markers. Fields marked +" +
+		comments.MarkerKey + " are the keys of their list.",
+	Aliases:      []string{"gen-paths"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorPaths, flag: "filename-paths", generate: GeneratePaths},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genpathsCmd)
+	addLoadFlags(genpathsCmd)
+	genpathsCmd.Flags().StringVarP(&filenamePaths, "filename-paths", "", "zz_generated.paths.go", "The filename of generated gNMI path builder files.")
+	addOutputFlags(genpathsCmd, "gNMI path builders")
+}
+
+// GeneratePaths generates the gNMI path builders of every managed resource of
+// the supplied package. Builders that the package declares outside the
+// generated file are not generated.
+func GeneratePaths(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	file := filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename)
+
+	var bs []gnmipath.Builder
+	var errs []string
+	names := map[string]string{}
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !p.Managed()(o) {
+			continue
+		}
+		rbs, err := gnmipath.Builders(p.Comments, o)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, b := range rbs {
+			if other, ok := names[b.Name]; ok {
+				errs = append(errs, errors.Errorf("path builder %s of %s collides with that of %s", b.Name, o.Name(), other).Error())
+				continue
+			}
+			names[b.Name] = o.Name()
+			if d := p.Types.Scope().Lookup(b.Name); d != nil && p.Fset.Position(d.Pos()).Filename != file {
+				continue
+			}
+			bs = append(bs, b)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWritePaths)
+	}
+
+	err := generate.WriteCode(p.Package, file, func(f *jen.File) {
+		gnmipath.Write(f, p.Types, i.GNMI.Path, bs)
+	}, append([]generate.WriteOption{
+		generate.WithHeaders(header),
+		generate.WithImportAliases(map[string]string{i.GNMI.Path: i.GNMI.Alias}),
+	}, wo...)...)
+
+	return errors.Wrap(err, errWritePaths)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+	"github.com/netw-device-driver/ndd-tools/internal/rbac"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteClusterRole = "cannot write ClusterRole"
+)
+
+var (
+	rbacDir          string
+	networkNodeGroup string
+)
+
+// ControllerVerbs are the verbs a controller is granted on the resources it
+// reconciles, and on their status subresources.
+var ControllerVerbs = []string{"get", "list", "watch", "update", "patch"}
+
+var genrbacCmd = &cobra.Command{
+	Use:   "generate-rbac",
+	Short: "generate ndd RBAC ClusterRoles.",
+	Long: "generate a ClusterRole per API package granting its controller access to every managed resource, " +
+		"NetworkNode and NetworkNodeUsage of the package, and to the NetworkNode and NetworkNodeUsage kinds of " +
+		"ndd-core. Additional rules may be declared using the +" + rbac.MarkerRBAC + " marker on packages or types.",
+	Aliases:      []string{"gen-rbac"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorRBAC, dirFlag: "rbac-dir", groupFlag: "network-node-group", generate: GenerateRBAC, typeErrorsOK: true},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genrbacCmd)
+	addLoadFlags(genrbacCmd)
+	genrbacCmd.Flags().StringVarP(&rbacDir, "rbac-dir", "", "package/rbac", "The directory, relative to the module root, to which ClusterRoles are written.")
+	genrbacCmd.Flags().StringVarP(&networkNodeGroup, "network-node-group", "", "dvr.ndd.henderiw.be", "The API group of the NetworkNode and NetworkNodeUsage kinds of ndd-core.")
+	addOutputFlags(genrbacCmd, "ClusterRoles")
+}
+
+// GenerateRBAC generates a ClusterRole for the supplied package, named after
+// its API group and version. It is written to the directory of the supplied
+// config.Generator, and grants managed resources access to the NetworkNode
+// kinds of its group. Packages without resources or RBAC markers are skipped.
+func GenerateRBAC(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	m := comments.ParseMarkers(comments.Package(p.Package))
+	resources := []string{}
+	managed := false
+	for _, n := range p.Types.Scope().Names() {
+		o, ok := p.Types.Scope().Lookup(n).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		om := comments.ParseMarkers(p.Comments.For(o) + "\n" + p.Comments.Before(o))
+		for k, v := range om {
+			m[k] = append(m[k], v...)
+		}
+		for _, k := range crdKinds {
+			if !k.matches()(o) {
+				continue
+			}
+			names, _ := crd.NamesOf(om, o.Name())
+			resources = append(resources, names.Plural, names.Plural+"/status")
+			managed = managed || match.Managed()(o)
+			break
+		}
+	}
+
+	rules, err := rbac.RulesOf(m)
+	if err != nil {
+		return errors.Wrap(err, errWriteClusterRole)
+	}
+	if len(resources) == 0 && len(rules) == 0 {
+		return nil
+	}
+
+	group, version, err := crd.GroupVersion(p.Package)
+	if err != nil {
+		return errors.Wrap(err, errWriteClusterRole)
+	}
+	if len(resources) > 0 {
+		rules = append(rules, rbac.Rule{APIGroups: []string{group}, Resources: resources, Verbs: ControllerVerbs})
+	}
+	if managed {
+		rules = append(rules, NetworkNodeRules(g.Group)...)
+	}
+
+	data, err := rbac.Marshal(rbac.NewClusterRole(group+":"+version, rules...))
+	if err != nil {
+		return errors.Wrap(err, errWriteClusterRole)
+	}
+	dir := g.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.Root(), dir)
+	}
+	return errors.Wrap(generate.WriteData(filepath.Join(dir, group+"_"+version+".yaml"), data, wo...), errWriteClusterRole)
+}
+
+// NetworkNodeRules returns the rules that grant the controller of a managed
+// resource access to the NetworkNode and NetworkNodeUsage kinds of the
+// supplied API group. Controllers read the NetworkNodes their resources
+// reference, and track that usage using NetworkNodeUsages.
+func NetworkNodeRules(group string) []rbac.Rule {
+	return []rbac.Rule{
+		{
+			APIGroups: []string{group},
+			Resources: []string{"networknodes", "networknodes/status"},
+			Verbs:     ControllerVerbs,
+		},
+		{
+			APIGroups: []string{group},
+			Resources: []string{"networknodeusages", "networknodeusages/status"},
+			Verbs:     append([]string{"create", "delete"}, ControllerVerbs...),
+		},
+	}
+}
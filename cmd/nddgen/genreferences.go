@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteReferences = "cannot write leafref and resource index methods"
+)
+
+var filenameReferences string
+
+var genreferencesCmd = &cobra.Command{
+	Use:   "generate-references",
+	Short: "generate ndd leafref and resource index methods.",
+	Long: "generate methods that compute the external leafrefs and resource indexes of every managed resource from " +
+		"its spec. Leafrefs are computed from fields marked +" + comments.MarkerLeafRef + "=<path>, e.g. " +
+		"+" + comments.MarkerLeafRef + "=/interface/name, and resource indexes from fields marked +" + comments.MarkerKey + ".",
+	Aliases:      []string{"gen-references"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorReferences, flag: "filename-references", generate: GenerateReferences},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genreferencesCmd)
+	addLoadFlags(genreferencesCmd)
+	genreferencesCmd.Flags().StringVarP(&filenameReferences, "filename-references", "", "zz_generated.references.go", "The filename of generated leafref and resource index files.")
+	addOutputFlags(genreferencesCmd, "leafref and resource index methods")
+}
+
+// GenerateReferences generates the ComputeExternalLeafRefs and
+// ComputeResourceIndexes methods of every managed resource of the supplied
+// package.
+func GenerateReferences(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	generated := p.Managed()
+
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !generated(o) {
+			continue
+		}
+		if err := method.CheckReferences(p.Comments, o); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteReferences)
+	}
+
+	err := generate.WriteMethods(p.Package, ReferenceMethods(g.Receiver, p.Comments), filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithMatcher(generated),
+		}, wo...)...,
+	)
+
+	return errors.Wrap(err, errWriteReferences)
+}
+
+// ReferenceMethods returns the methods that compute the external leafrefs and
+// resource indexes of a managed resource from the markers of its spec fields,
+// per the supplied comments.
+func ReferenceMethods(receiver string, c comments.Comments) method.Set {
+	return method.Set{
+		"ComputeExternalLeafRefs": method.NewComputeExternalLeafRefs(receiver, c),
+		"ComputeResourceIndexes":  method.NewComputeResourceIndexes(receiver, c),
+	}
+}
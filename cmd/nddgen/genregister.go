@@ -0,0 +1,230 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// KubeSchemaPath is the import path of the Kubernetes API machinery schema
+// package, which defines schema.GroupVersion.
+const KubeSchemaPath = "k8s.io/apimachinery/pkg/runtime/schema"
+
+const (
+	errWriteRegister = "cannot write scheme registration"
+)
+
+var filenameRegister string
+
+var genregisterCmd = &cobra.Command{
+	Use:   "generate-register",
+	Short: "generate ndd scheme registration.",
+	Long: "generate the Kind, GroupKind, KindAPIVersion and GroupVersionKind of every managed resource, and register " +
+		"it and its list with the SchemeBuilder of its package. The API group and version are those of the package's " +
+		"GroupVersion or SchemeGroupVersion variable, or else those declared by its +" + crd.MarkerGroupName + " marker.",
+	Aliases:      []string{"gen-register"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorRegister, flag: "filename-register", generate: GenerateRegister, typeErrorsOK: true},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genregisterCmd)
+	addLoadFlags(genregisterCmd)
+	genregisterCmd.Flags().StringVarP(&filenameRegister, "filename-register", "", "zz_generated.register.go", "The filename of generated scheme registration files.")
+	addOutputFlags(genregisterCmd, "scheme registration")
+}
+
+// GenerateRegister generates the type metadata of every managed resource of the
+// supplied package, and registers each managed resource and its list with the
+// package's SchemeBuilder. Type metadata already declared outside the generated
+// file, e.g. by a hand written register.go, is not generated.
+func GenerateRegister(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	file := filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename)
+
+	var kinds [][2]string
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !match.Managed()(o) {
+			continue
+		}
+		l := p.Types.Scope().Lookup(o.Name() + "List")
+		if l == nil || !match.ManagedList()(l) {
+			errs = append(errs, errors.Errorf("managed resource %s has no %sList", o.Name(), o.Name()).Error())
+			continue
+		}
+		kinds = append(kinds, [2]string{o.Name(), l.Name()})
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteRegister)
+	}
+
+	var gv GroupVersionCode
+	if len(kinds) > 0 {
+		var err error
+		if gv, err = schemeGroupVersion(p); err != nil {
+			return errors.Wrap(err, errWriteRegister)
+		}
+		if !hasSchemeBuilder(p) {
+			return errors.Wrapf(errors.Errorf("package %s has no SchemeBuilder with a Register method", p.PkgPath), errWriteRegister)
+		}
+	}
+
+	declared := func(name string) bool {
+		o := p.Types.Scope().Lookup(name)
+		return o != nil && p.Fset.Position(o.Pos()).Filename != file
+	}
+
+	err := generate.WriteCode(p.Package, file, func(f *jen.File) {
+		register := make([]jen.Code, 0, 2*len(kinds))
+		for _, k := range kinds {
+			WriteTypeMetadata(f, k[0], gv, declared)
+			register = append(register, jen.Op("&").Id(k[0]).Values(), jen.Op("&").Id(k[1]).Values())
+		}
+		if len(register) == 0 {
+			return
+		}
+		f.Func().Id("init").Params().Block(
+			jen.Id("SchemeBuilder").Dot("Register").Call(register...),
+		)
+	}, append([]generate.WriteOption{generate.WithHeaders(header)}, wo...)...)
+
+	return errors.Wrap(err, errWriteRegister)
+}
+
+// GroupVersionCode returns code referring to a schema.GroupVersion.
+type GroupVersionCode struct {
+	// GroupVersion returns the schema.GroupVersion.
+	GroupVersion func() jen.Code
+
+	// Group returns its API group.
+	Group func() jen.Code
+
+	// String returns its string form, e.g. example.org/v1.
+	String func() jen.Code
+}
+
+// WriteTypeMetadata writes the Kind, GroupKind, KindAPIVersion and
+// GroupVersionKind of the supplied kind to the supplied file, omitting any that
+// are already declared.
+func WriteTypeMetadata(f *jen.File, kind string, gv GroupVersionCode, declared func(name string) bool) {
+	k := kind + "Kind"
+	if !declared(k) {
+		f.Commentf("%s is the kind of a %s.", k, kind)
+		f.Const().Id(k).Op("=").Lit(kind)
+	}
+
+	vars := []struct {
+		name string
+		code jen.Code
+	}{
+		{name: kind + "GroupKind", code: jen.Qual(KubeSchemaPath, "GroupKind").Values(jen.Dict{
+			jen.Id("Group"): gv.Group(),
+			jen.Id("Kind"):  jen.Id(k),
+		}).Dot("String").Call()},
+		{name: kind + "KindAPIVersion", code: jen.Id(k).Op("+").Lit(".").Op("+").Add(gv.String())},
+		{name: kind + "GroupVersionKind", code: jen.Add(gv.GroupVersion()).Dot("WithKind").Call(jen.Id(k))},
+	}
+	defs := make([]jen.Code, 0, len(vars))
+	for _, v := range vars {
+		if !declared(v.name) {
+			defs = append(defs, jen.Id(v.name).Op("=").Add(v.code))
+		}
+	}
+	if len(defs) == 0 {
+		return
+	}
+	f.Commentf("%s type metadata.", kind)
+	f.Var().Defs(defs...)
+}
+
+// schemeGroupVersion returns code referring to the schema.GroupVersion of the
+// supplied package. This is its GroupVersion or SchemeGroupVersion variable if
+// it declares one, as groupversion_info.go files do, or else a literal built
+// from its +groupName marker.
+func schemeGroupVersion(p *runner.Package) (GroupVersionCode, error) {
+	for _, n := range []string{"GroupVersion", "SchemeGroupVersion"} {
+		v, ok := p.Types.Scope().Lookup(n).(*types.Var)
+		if !ok {
+			continue
+		}
+		if t, ok := v.Type().(*types.Named); ok && t.Obj().Pkg() != nil &&
+			t.Obj().Pkg().Path() == KubeSchemaPath && t.Obj().Name() == "GroupVersion" {
+			return GroupVersionCode{
+				GroupVersion: func() jen.Code { return jen.Id(n) },
+				Group:        func() jen.Code { return jen.Id(n).Dot("Group") },
+				String:       func() jen.Code { return jen.Id(n).Dot("String").Call() },
+			}, nil
+		}
+	}
+
+	group, version, err := crd.GroupVersion(p.Package)
+	if err != nil {
+		return GroupVersionCode{}, err
+	}
+	return GroupVersionCode{
+		GroupVersion: func() jen.Code {
+			return jen.Qual(KubeSchemaPath, "GroupVersion").Values(jen.Dict{
+				jen.Id("Group"):   jen.Lit(group),
+				jen.Id("Version"): jen.Lit(version),
+			})
+		},
+		Group:  func() jen.Code { return jen.Lit(group) },
+		String: func() jen.Code { return jen.Lit(group + "/" + version) },
+	}, nil
+}
+
+// hasSchemeBuilder returns true if the supplied package declares a
+// SchemeBuilder variable with a Register method that accepts objects, such as
+// a controller-runtime scheme.Builder.
+func hasSchemeBuilder(p *runner.Package) bool {
+	v, ok := p.Types.Scope().Lookup("SchemeBuilder").(*types.Var)
+	if !ok {
+		return false
+	}
+	m, _, _ := types.LookupFieldOrMethod(v.Type(), true, p.Types, "Register")
+	fn, ok := m.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if !sig.Variadic() || sig.Params().Len() != 1 {
+		return false
+	}
+	s, ok := sig.Params().At(0).Type().(*types.Slice)
+	if !ok {
+		return false
+	}
+	_, ok = s.Elem().Underlying().(*types.Interface)
+	return ok
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+	"github.com/netw-device-driver/ndd-tools/internal/sample"
+)
+
+const (
+	errWriteSamples = "cannot write example manifests"
+)
+
+var samplesDir string
+
+var gensamplesCmd = &cobra.Command{
+	Use:   "generate-samples",
+	Short: "generate ndd example manifests.",
+	Long: "generate a minimal and a fully populated example manifest of every managed resource, using the defaults " +
+		"and allowed values of its fields where they are declared. Packages must declare their API group using the +" +
+		crd.MarkerGroupName + " marker.",
+	Aliases:      []string{"gen-samples"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorSamples, dirFlag: "samples-dir", generate: GenerateSamples, typeErrorsOK: true},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gensamplesCmd)
+	addLoadFlags(gensamplesCmd)
+	gensamplesCmd.Flags().StringVarP(&samplesDir, "samples-dir", "", "examples", "The directory, relative to the module root, to which example manifests are written.")
+	addOutputFlags(gensamplesCmd, "example manifests")
+}
+
+// GenerateSamples generates example manifests of every managed resource of
+// the supplied package. They are written to <group>/<kind>.yaml under the
+// directory of the supplied config.Generator. The supplied header is not used,
+// since it is Go source.
+func GenerateSamples(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	dir := g.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.Root(), dir)
+	}
+
+	var b *sample.Builder
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o, ok := p.Types.Scope().Lookup(n).(*types.TypeName)
+		if !ok || !p.Managed()(o) {
+			continue
+		}
+		if b == nil {
+			var err error
+			if b, err = sample.NewBuilder(p.Package); err != nil {
+				return errors.Wrap(err, errWriteSamples)
+			}
+		}
+		if err := writeSamples(b, o, dir, wo...); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteSamples)
+	}
+	return nil
+}
+
+func writeSamples(b *sample.Builder, o types.Object, dir string, wo ...generate.WriteOption) error {
+	minimal, full, err := b.Build(o)
+	if err != nil {
+		return err
+	}
+	data, err := sample.Marshal(minimal, full)
+	if err != nil {
+		return err
+	}
+	return generate.WriteData(filepath.Join(dir, b.Group(), strings.ToLower(o.Name())+".yaml"), data, wo...)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	errWriteValidation = "cannot write validation methods"
+)
+
+var filenameValidation string
+
+var genvalidationCmd = &cobra.Command{
+	Use:   "generate-validation",
+	Short: "generate ndd validation methods.",
+	Long: "generate a Validate method for the spec of every managed resource that returns the errors of every field " +
+		"that violates its kubebuilder validation markers, or its +" + comments.MarkerRange + " and +" +
+		comments.MarkerLength + " markers.",
+	Aliases:      []string{"gen-validation"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerators(cmd, []namedGenerator{
+			{name: config.GeneratorValidation, flag: "filename-validation", generate: GenerateValidation},
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genvalidationCmd)
+	addLoadFlags(genvalidationCmd)
+	genvalidationCmd.Flags().StringVarP(&filenameValidation, "filename-validation", "", "zz_generated.validate.go", "The filename of generated validation files.")
+	addOutputFlags(genvalidationCmd, "validation methods")
+}
+
+// GenerateValidation generates the Validate method of the spec of every
+// managed resource of the supplied package.
+func GenerateValidation(g config.Generator, i config.Imports, header string, p *runner.Package, wo ...generate.WriteOption) error {
+	specs := p.ManagedSpecs()
+
+	var errs []string
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !specs(o) {
+			continue
+		}
+		if err := method.CheckValidation(p.Comments, o); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errors.New(strings.Join(errs, "; ")), errWriteValidation)
+	}
+
+	err := generate.WriteMethods(p.Package, ValidationMethods(g.Receiver, p.Comments), filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+		append([]generate.WriteOption{
+			generate.WithHeaders(header),
+			generate.WithMatcher(specs),
+		}, wo...)...,
+	)
+
+	return errors.Wrap(err, errWriteValidation)
+}
+
+// ValidationMethods returns the methods that validate the spec of a managed
+// resource per the markers of its fields, per the supplied comments.
+func ValidationMethods(receiver string, c comments.Comments) method.Set {
+	return method.Set{
+		"Validate": method.NewValidate(receiver, "spec", c),
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// validationTypes declares a managed resource whose spec has fields with each
+// kind of validation marker.
+const validationTypes = `package v1
+
+import (
+	nddv1 "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=fast;slow
+type Mode string
+
+type Item struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type ThingSpec struct {
+	nddv1.ResourceSpec ` + "`json:\",inline\"`" + `
+
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	Count int32 ` + "`json:\"count\"`" + `
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:ExclusiveMinimum=true
+	// +kubebuilder:validation:Maximum=1
+	// +kubebuilder:validation:ExclusiveMaximum=true
+	Ratio float64 ` + "`json:\"ratio\"`" + `
+
+	// +kubebuilder:validation:Pattern=` + "`^[a-z]+$`" + `
+	Labels map[string]string ` + "`json:\"labels,omitempty\"`" + `
+
+	Mode Mode ` + "`json:\"mode\"`" + `
+
+	// +ndd:validation:Range="1..4094"
+	Vlan *int ` + "`json:\"vlan,omitempty\"`" + `
+
+	// +ndd:validation:Length="1..8"
+	Name string ` + "`json:\"name\"`" + `
+
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:Maximum=65535
+	Ports [][]int32 ` + "`json:\"ports,omitempty\"`" + `
+
+	Items []Item ` + "`json:\"items,omitempty\"`" + `
+}
+
+type ThingStatus struct {
+	nddv1.ResourceStatus ` + "`json:\",inline\"`" + `
+}
+
+type Thing struct {
+	metav1.TypeMeta   ` + "`json:\",inline\"`" + `
+	metav1.ObjectMeta ` + "`json:\"metadata,omitempty\"`" + `
+
+	Spec   ThingSpec   ` + "`json:\"spec,omitempty\"`" + `
+	Status ThingStatus ` + "`json:\"status,omitempty\"`" + `
+}
+`
+
+// validationTest checks the field paths of the errors returned by the
+// generated Validate method.
+const validationTest = `package v1
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func valid() ThingSpec {
+	vlan := 100
+	return ThingSpec{
+		Count:  5,
+		Ratio:  0.5,
+		Labels: map[string]string{"a": "abc"},
+		Mode:   "fast",
+		Vlan:   &vlan,
+		Name:   "thing",
+		Ports:  [][]int32{{80}, {443, 8443}},
+		Items:  []Item{{Name: "a"}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	zero, big := 0, 4095
+	cases := map[string]struct {
+		mutate func(s *ThingSpec)
+		want   []string
+	}{
+		"Valid":            {mutate: func(s *ThingSpec) {}},
+		"BelowMinimum":     {mutate: func(s *ThingSpec) { s.Count = 0 }, want: []string{"spec.count"}},
+		"AtMinimum":        {mutate: func(s *ThingSpec) { s.Count = 1 }},
+		"AboveMaximum":     {mutate: func(s *ThingSpec) { s.Count = 11 }, want: []string{"spec.count"}},
+		"AtMaximum":        {mutate: func(s *ThingSpec) { s.Count = 10 }},
+		"AtExclusiveMin":   {mutate: func(s *ThingSpec) { s.Ratio = 0 }, want: []string{"spec.ratio"}},
+		"AtExclusiveMax":   {mutate: func(s *ThingSpec) { s.Ratio = 1 }, want: []string{"spec.ratio"}},
+		"MapValuePattern":  {mutate: func(s *ThingSpec) { s.Labels["b"] = "B" }, want: []string{"spec.labels[b]"}},
+		"NamedTypeEnum":    {mutate: func(s *ThingSpec) { s.Mode = "medium" }, want: []string{"spec.mode"}},
+		"BelowRange":       {mutate: func(s *ThingSpec) { s.Vlan = &zero }, want: []string{"spec.vlan"}},
+		"AboveRange":       {mutate: func(s *ThingSpec) { s.Vlan = &big }, want: []string{"spec.vlan"}},
+		"NilRange":         {mutate: func(s *ThingSpec) { s.Vlan = nil }},
+		"BelowLength":      {mutate: func(s *ThingSpec) { s.Name = "" }, want: []string{"spec.name"}},
+		"AboveLength":      {mutate: func(s *ThingSpec) { s.Name = "things-123" }, want: []string{"spec.name"}},
+		"TooManyItems":     {mutate: func(s *ThingSpec) { s.Ports = append(s.Ports, nil) }, want: []string{"spec.ports"}},
+		"NestedSliceItem":  {mutate: func(s *ThingSpec) { s.Ports[1][1] = 65536 }, want: []string{"spec.ports[1][1]"}},
+		"StructSliceField": {mutate: func(s *ThingSpec) { s.Items = append(s.Items, Item{}) }, want: []string{"spec.items[1].name"}},
+		"Several": {
+			mutate: func(s *ThingSpec) { s.Count, s.Mode = 0, "" },
+			want:   []string{"spec.count", "spec.mode"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := valid()
+			tc.mutate(&s)
+			var got []string
+			if err := s.Validate(); err != nil {
+				for _, e := range err.(field.Aggregate).Errors() {
+					got = append(got, e.(*field.Error).Field)
+				}
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("Validate(): want errors of %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+`
+
+// TestGenerateValidation generates the Validate method of a fixture module
+// whose spec uses each kind of validation marker, then runs a test of the
+// field paths of the errors it returns.
+func TestGenerateValidation(t *testing.T) {
+	dir := writeFixture(t, 1)
+	writeFiles(t, dir, map[string]string{
+		filepath.Join("apis", "v1", "types.go"):         validationTypes,
+		filepath.Join("apis", "v1", "validate_test.go"): validationTest,
+	})
+
+	err := runner.Run(runner.Config{
+		Name:       "generate-validation",
+		Paths:      []string{"./..."},
+		Dir:        dir,
+		Env:        fixtureEnv,
+		Force:      true,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+		Generators: []runner.Generator{{Name: config.GeneratorValidation, Generate: GenerateValidation}},
+	})
+	if err != nil {
+		t.Fatalf("Run(...): %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "apis", "v1", "zz_generated.validate.go")); err != nil {
+		t.Fatalf("Run(...): validation not written: %v", err)
+	}
+
+	runGo(t, dir, "vet", "./...")
+	runGo(t, dir, "test", "./...")
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+const (
+	errUnknownOutput = "unknown output format"
+	errWriteOutput   = "cannot write output"
+)
+
+var inspectOutput string
+
+// A packageInspection describes how ndd-gen classifies the named types of a
+// package.
+type packageInspection struct {
+	Package string           `json:"package"`
+	Tags    string           `json:"tags,omitempty"`
+	Types   []typeInspection `json:"types"`
+}
+
+// A typeInspection describes which generators match a named type.
+type typeInspection struct {
+	Name       string                `json:"name"`
+	Generators []generatorInspection `json:"generators,omitempty"`
+}
+
+// A generatorInspection describes the methods a generator would write for a
+// named type it matches.
+type generatorInspection struct {
+	Name     string   `json:"name"`
+	Filename string   `json:"filename"`
+	Enabled  bool     `json:"enabled"`
+	Disabled bool     `json:"disabledByMarker"`
+	Written  []string `json:"written,omitempty"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+var inspectCmd = &cobra.Command{
+	Use:          "inspect",
+	Short:        "inspect how ndd-gen classifies API types.",
+	Long:         "inspect lists every named type, the method set generators it matches, and the methods that would be written or skipped.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if inspectOutput != outputTable && inspectOutput != outputJSON {
+			return errors.Errorf("%s : %s", errUnknownOutput, inspectOutput)
+		}
+		c, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := runner.CheckPaths(c); err != nil {
+			return err
+		}
+
+		groups, err := loadPackages(c.Paths)
+		if err != nil {
+			return err
+		}
+
+		result := make([]packageInspection, 0, len(groups))
+		for _, variants := range groups {
+			for _, pv := range variants {
+				// Packages that do not type check, for example because they
+				// lack generated methods, are still inspected.
+				for _, err := range pv.Errors {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", err)
+				}
+				if pv.Types == nil || len(pv.GoFiles) == 0 {
+					continue
+				}
+				pi := inspect(c.For(pv.PkgPath), pv.Package)
+				if len(tags) > 1 {
+					pi.Tags = pv.Tags
+				}
+				result = append(result, pi)
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Package < result[j].Package })
+
+		if inspectOutput == outputJSON {
+			return errors.Wrap(printInspectionJSON(cmd.OutOrStdout(), result), errWriteOutput)
+		}
+		return errors.Wrap(printInspectionTable(cmd.OutOrStdout(), result), errWriteOutput)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	addLoadFlags(inspectCmd)
+	inspectCmd.Flags().StringVarP(&inspectOutput, "output", "o", outputTable, "Output format, one of table or json.")
+}
+
+// inspect classifies every named type of the supplied package using the
+// method set generators of generate-methodsets.
+func inspect(c *config.Config, p *packages.Package) packageInspection {
+	pi := packageInspection{Package: p.PkgPath, Types: []typeInspection{}}
+	enabled := runner.NewPackage(p).Enabled
+
+	for _, n := range p.Types.Scope().Names() {
+		o, ok := p.Types.Scope().Lookup(n).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		ti := typeInspection{Name: o.Name()}
+		for _, ms := range methodSets {
+			if !ms.matches()(o) {
+				continue
+			}
+			g := c.Generator(ms.name)
+			gi := generatorInspection{
+				Name:     ms.name,
+				Filename: g.Filename,
+				Enabled:  g.IsEnabled(),
+				Disabled: !enabled(o),
+			}
+			if gi.Enabled && !gi.Disabled {
+				definedOutside := method.DefinedOutside(p.Fset, filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename))
+				for _, name := range sortedNames(ms.methods(g.Receiver, c.Imports)) {
+					if definedOutside(o, name) {
+						gi.Skipped = append(gi.Skipped, name)
+						continue
+					}
+					gi.Written = append(gi.Written, name)
+				}
+			}
+			ti.Generators = append(ti.Generators, gi)
+		}
+		pi.Types = append(pi.Types, ti)
+	}
+	return pi
+}
+
+func sortedNames(s method.Set) []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printInspectionJSON(out io.Writer, pis []packageInspection) error {
+	e := json.NewEncoder(out)
+	e.SetIndent("", "  ")
+	return e.Encode(pis)
+}
+
+func printInspectionTable(out io.Writer, pis []packageInspection) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tTYPE\tGENERATOR\tDISABLED\tWRITTEN\tSKIPPED")
+	for _, pi := range pis {
+		pkg := pi.Package
+		if pi.Tags != "" {
+			pkg = fmt.Sprintf("%s [%s]", pi.Package, pi.Tags)
+		}
+		for _, ti := range pi.Types {
+			if len(ti.Generators) == 0 {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t-\n", pkg, ti.Name)
+				continue
+			}
+			for _, gi := range ti.Generators {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", pkg, ti.Name, gi.Name, disabledBy(gi), joinOrDash(gi.Written), joinOrDash(gi.Skipped))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func disabledBy(gi generatorInspection) string {
+	switch {
+	case gi.Disabled:
+		return "marker"
+	case !gi.Enabled:
+		return "config"
+	}
+	return "-"
+}
+
+func joinOrDash(s []string) string {
+	if len(s) == 0 {
+		return "-"
+	}
+	return strings.Join(s, ",")
+}
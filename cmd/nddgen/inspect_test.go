@@ -0,0 +1,53 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestInspectOutput(t *testing.T) {
+	dir := writeFixture(t, 1)
+	t.Cleanup(func() {
+		inspectOutput, patterns, env = outputTable, nil, nil
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	})
+
+	args := []string{"inspect", "--output", outputJSON, "--dir", dir, "--paths", "./..."}
+	for _, e := range fixtureEnv {
+		args = append(args, "--env", e)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute(): %v\n%s", err, stderr)
+	}
+
+	var result []packageInspection
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Execute(): want JSON written to the output of the command: %v\n%s", err, stdout)
+	}
+	if len(result) == 0 {
+		t.Errorf("Execute(): want inspected packages, got none")
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+var (
+	configFile string
+	patterns   []string
+	tags       []string
+	dir        string
+	env        []string
+)
+
+// addLoadFlags adds the flags that determine which packages are loaded, and
+// how, to the supplied command.
+func addLoadFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&configFile, "config", "", "", "The ndd-gen config file. Defaults to "+config.Filename+" at the root of the module, if it exists.")
+	cmd.Flags().StringSliceVarP(&patterns, "paths", "", nil, "Package(s) to load, for example github.com/netw-device-driver/ndd-core/apis/... May be repeated.")
+	cmd.Flags().StringArrayVarP(&tags, "tags", "", nil, "A comma separated list of build tags to load packages with. May be repeated to load packages once per tag set.")
+	cmd.Flags().StringVarP(&dir, "dir", "", "", "The directory in which to load packages, and from which to discover the module root. Defaults to the working directory.")
+	cmd.Flags().StringArrayVarP(&env, "env", "", nil, "An environment variable, e.g. GOOS=linux, to load packages with. May be repeated.")
+}
+
+// loadPackages loads the packages matching the supplied patterns per the
+// --tags, --dir and --env flags.
+func loadPackages(patterns []string) ([][]runner.Variant, error) {
+	return runner.Config{Tags: tags, Dir: dir, Env: env}.LoadPackages(patterns)
+}
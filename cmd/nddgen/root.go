@@ -17,15 +17,24 @@ limitations under the License.
 package nddgen
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
+// Set at build time using -ldflags.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "ndd-gen",
-	Short: "ndd-gen generates ndd API type methods.",
+	Use:     "ndd-gen",
+	Short:   "ndd-gen generates ndd API type methods.",
+	Version: version,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 	},
 }
@@ -33,6 +42,7 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.SetVersionTemplate(fmt.Sprintf("ndd-gen %s (commit %s, built %s)\n", version, commit, date))
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/netw-device-driver/ndd-tools/internal/cache"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+var (
+	headerFile string
+	verify     bool
+	dryRun     bool
+	outputDir  string
+	jobs       int
+	cacheFile  string
+	force      bool
+)
+
+// A namedGenerator is a runner.GeneratorFunc and the name of the
+// config.Generator that configures it. The filename and directory it writes
+// may be overridden by the flag and dirFlag, if any, its unit tests enabled by
+// the testsFlag, if any, the formats it writes overridden by the formatsFlag,
+// if any, and the ndd-core API group it refers to overridden by the groupFlag,
+// if any.
+type namedGenerator struct {
+	name        string
+	flag        string
+	dirFlag     string
+	testsFlag   string
+	formatsFlag string
+	groupFlag   string
+	generate    runner.GeneratorFunc
+
+	// typeErrorsOK allows the generator to run for packages that do not type
+	// check, for example because they lack the methods it generates.
+	typeErrorsOK bool
+
+	// inputs are the names of the generators whose generated files are inputs
+	// of the generator. See runner.Generator.
+	inputs []string
+}
+
+// generator returns the runner.Generator of the namedGenerator.
+func (ng namedGenerator) generator() runner.Generator {
+	return runner.Generator{Name: ng.name, Generate: ng.generate, TypeErrorsOK: ng.typeErrorsOK, Inputs: ng.inputs}
+}
+
+// addOutputFlags adds the flags that determine how and where the supplied
+// command writes what it generates.
+func addOutputFlags(cmd *cobra.Command, what string) {
+	cmd.Flags().StringVarP(&headerFile, "header-file", "", "", "The contents of this file will be added to the top of all generated files.")
+	cmd.Flags().BoolVarP(&verify, "verify", "", false, "Compare generated "+what+" against the files on disk without writing them, and fail if they differ.")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "Print generated "+what+" to stdout instead of writing them.")
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "", "", "Write generated "+what+" to this directory, mirroring the package layout, instead of next to their package.")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "The number of packages to generate concurrently.")
+	cmd.Flags().StringVarP(&cacheFile, "cache-file", "", "", "The file in which to cache generation results. Defaults to "+cache.Filename+" at the root of the module.")
+	cmd.Flags().BoolVarP(&force, "force", "", false, "Generate all packages, even those that are unchanged since they were last generated.")
+}
+
+// runGenerators runs the supplied generators, in order, for every configured
+// package and writes, verifies or prints what they generate according to the
+// flags of the supplied command.
+func runGenerators(cmd *cobra.Command, gens []namedGenerator) error {
+	rc := runner.Config{
+		Name:       cmd.Name(),
+		Version:    version,
+		ConfigFile: configFile,
+		Tags:       tags,
+		Dir:        dir,
+		Env:        env,
+		Verify:     verify,
+		DryRun:     dryRun,
+		OutputDir:  outputDir,
+		Jobs:       jobs,
+		CacheFile:  cacheFile,
+		Force:      force,
+		Stdout:     cmd.OutOrStdout(),
+		Stderr:     cmd.ErrOrStderr(),
+		Generators: make([]runner.Generator, 0, len(gens)),
+	}
+	for _, ng := range gens {
+		rc.Generators = append(rc.Generators, ng.generator())
+	}
+	c, err := loadConfig(cmd, gens...)
+	if err != nil {
+		return err
+	}
+
+	// Progress is printed to stderr so that it does not mix with the files
+	// printed by --dry-run, or the differences printed by --verify.
+	fmt.Fprintln(rc.Stderr, "ndd-gen started ...")
+	if err := runner.RunWith(rc, c); err != nil {
+		return err
+	}
+	fmt.Fprintln(rc.Stderr, "ndd-gen finished ...")
+	return nil
+}
+
+// loadConfig loads the config file supplied via --config, or discovered at the
+// module root, and applies any flags explicitly set on the supplied command,
+// including the filename flags of the supplied generators.
+func loadConfig(cmd *cobra.Command, gens ...namedGenerator) (*config.Config, error) {
+	c, err := runner.Config{ConfigFile: configFile, Dir: dir}.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Flags().Changed("header-file") {
+		c.HeaderFile = headerFile
+	}
+	if cmd.Flags().Changed("paths") {
+		c.Paths = patterns
+	}
+	for _, ng := range gens {
+		g := c.Generators[ng.name]
+		if ng.flag != "" && cmd.Flags().Changed(ng.flag) {
+			g.Filename, _ = cmd.Flags().GetString(ng.flag)
+		}
+		if ng.dirFlag != "" && cmd.Flags().Changed(ng.dirFlag) {
+			g.Dir, _ = cmd.Flags().GetString(ng.dirFlag)
+		}
+		if ng.testsFlag != "" && cmd.Flags().Changed(ng.testsFlag) {
+			t, _ := cmd.Flags().GetBool(ng.testsFlag)
+			g.Tests = &t
+		}
+		if ng.formatsFlag != "" && cmd.Flags().Changed(ng.formatsFlag) {
+			g.Formats, _ = cmd.Flags().GetStringSlice(ng.formatsFlag)
+		}
+		if ng.groupFlag != "" && cmd.Flags().Changed(ng.groupFlag) {
+			g.Group, _ = cmd.Flags().GetString(ng.groupFlag)
+		}
+		c.Generators[ng.name] = g
+	}
+	return c, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nddgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// benchmarkPackages is the number of packages of the fixture module the
+// benchmarks generate.
+const benchmarkPackages = 16
+
+// BenchmarkRun measures runs of generate-methodsets, including loading
+// packages and writing files, generating one package at a time and one per
+// CPU at a time. The latter is only run on machines with more than one CPU.
+func BenchmarkRun(b *testing.B) {
+	dir := writeFixture(b, benchmarkPackages)
+	jobs := []int{1}
+	if runtime.NumCPU() > 1 {
+		jobs = append(jobs, runtime.NumCPU())
+	}
+	for _, jobs := range jobs {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			rc := methodSetsConfig(dir, false, jobs)
+			for i := 0; i < b.N; i++ {
+				if err := runner.Run(rc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// packageGenerators are the generators BenchmarkPackage runs for every
+// package, keyed by the name of their config.Generator.
+var packageGenerators = []struct {
+	name     string
+	generate runner.GeneratorFunc
+}{
+	{name: config.GeneratorManaged, generate: GenerateManaged},
+	{name: config.GeneratorManagedList, generate: GenerateManagedList},
+	{name: config.GeneratorNetworkNode, generate: GenerateNetworkNode},
+	{name: config.GeneratorNetworkNodeUsage, generate: GenerateNetworkNodeUsage},
+	{name: config.GeneratorNetworkNodeUsageList, generate: GenerateNetworkNodeUsageList},
+	{name: config.GeneratorReferences, generate: GenerateReferences},
+	{name: config.GeneratorValidation, generate: GenerateValidation},
+	{name: config.GeneratorDefaults, generate: GenerateDefaults},
+	{name: config.GeneratorDiff, generate: GenerateDiff},
+}
+
+// BenchmarkPackage measures the work generators do per package, excluding
+// loading packages and writing files. The shared benchmark computes the
+// comments and matchers of each package once for all generators, as Run does,
+// while the per-generator benchmark computes them for every generator.
+func BenchmarkPackage(b *testing.B) {
+	dir := writeFixture(b, benchmarkPackages)
+	pkgs, err := packages.Load(&packages.Config{Mode: runner.LoadMode, Dir: dir, Env: append(os.Environ(), fixtureEnv...)}, "./...")
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := config.Default()
+	discard := []generate.WriteOption{
+		generate.WithFileWriter(func(string, []byte) error { return nil }),
+		generate.WithFileRemover(func(string) error { return nil }),
+	}
+	gen := func(b *testing.B, p *runner.Package, name string, fn runner.GeneratorFunc) {
+		if err := fn(c.Generator(name), c.Imports, "", p, discard...); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, pkg := range pkgs {
+				p := runner.NewPackage(pkg)
+				for _, g := range packageGenerators {
+					gen(b, p, g.name, g.generate)
+				}
+			}
+		}
+	})
+	b.Run("per-generator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, pkg := range pkgs {
+				for _, g := range packageGenerators {
+					gen(b, runner.NewPackage(pkg), g.name, g.generate)
+				}
+			}
+		}
+	})
+}
+
+// TestRunGeneratorsDryRun runs a command with --dry-run and checks that only
+// the generated files are printed to stdout, so that they may be piped.
+func TestRunGeneratorsDryRun(t *testing.T) {
+	dir := writeFixture(t, 1)
+	t.Cleanup(func() {
+		dryRun, patterns, env = false, nil, nil
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	})
+
+	args := []string{"generate-methodsets", "--dry-run", "--dir", dir, "--paths", "./..."}
+	for _, e := range fixtureEnv {
+		args = append(args, "--env", e)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute(): %v\n%s", err, stderr)
+	}
+
+	if !strings.HasPrefix(stdout.String(), "// ---- ") {
+		t.Errorf("Execute(): want stdout to begin with a generated file, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout.String(), "ndd-gen started") || strings.Contains(stdout.String(), "ndd-gen finished") {
+		t.Errorf("Execute(): want progress printed to stderr, not stdout")
+	}
+	if !strings.Contains(stderr.String(), "ndd-gen finished") {
+		t.Errorf("Execute(): want progress printed to stderr, got:\n%s", stderr)
+	}
+}
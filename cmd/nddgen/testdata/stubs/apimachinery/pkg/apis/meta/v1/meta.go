@@ -0,0 +1,19 @@
+// Package v1 is a minimal stand-in for the Kubernetes object metadata that
+// ndd resources embed.
+package v1
+
+// TypeMeta describes an individual object.
+type TypeMeta struct {
+	Kind       string
+	APIVersion string
+}
+
+// ObjectMeta is metadata that all persisted resources must have.
+type ObjectMeta struct {
+	Name string
+}
+
+// ListMeta describes metadata that synthetic resources must have.
+type ListMeta struct {
+	Continue string
+}
@@ -0,0 +1,94 @@
+// Package field is a minimal stand-in for the Kubernetes package that
+// describes errors of the fields of an object, which generated validation
+// methods refer to.
+package field
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Path of a field within an object.
+type Path struct {
+	s string
+}
+
+// NewPath returns the path of the supplied root field.
+func NewPath(name string) *Path { return &Path{s: name} }
+
+// Child returns the path of the supplied child field.
+func (p *Path) Child(name string) *Path { return &Path{s: p.s + "." + name} }
+
+// Index returns the path of the supplied index of a list.
+func (p *Path) Index(i int) *Path { return &Path{s: fmt.Sprintf("%s[%d]", p.s, i)} }
+
+// Key returns the path of the supplied key of a map.
+func (p *Path) Key(k string) *Path { return &Path{s: fmt.Sprintf("%s[%s]", p.s, k)} }
+
+// String returns the path, e.g. spec.items[0].name.
+func (p *Path) String() string { return p.s }
+
+// An ErrorType of an Error.
+type ErrorType string
+
+// Types of Errors.
+const (
+	ErrorTypeInvalid      ErrorType = "FieldValueInvalid"
+	ErrorTypeNotSupported ErrorType = "FieldValueNotSupported"
+)
+
+// An Error of a field.
+type Error struct {
+	Type     ErrorType
+	Field    string
+	BadValue interface{}
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s: %v: %s", e.Field, e.Type, e.BadValue, e.Detail)
+}
+
+// Invalid returns an Error of a field whose value is invalid.
+func Invalid(p *Path, v interface{}, detail string) *Error {
+	return &Error{Type: ErrorTypeInvalid, Field: p.String(), BadValue: v, Detail: detail}
+}
+
+// NotSupported returns an Error of a field whose value is not one of the
+// supplied values.
+func NotSupported(p *Path, v interface{}, valid []string) *Error {
+	return &Error{Type: ErrorTypeNotSupported, Field: p.String(), BadValue: v, Detail: strings.Join(valid, ", ")}
+}
+
+// An ErrorList of Errors.
+type ErrorList []*Error
+
+// An Aggregate of errors.
+type Aggregate interface {
+	error
+	Errors() []error
+}
+
+type aggregate []error
+
+func (a aggregate) Error() string {
+	s := make([]string, len(a))
+	for i, err := range a {
+		s[i] = err.Error()
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+func (a aggregate) Errors() []error { return a }
+
+// ToAggregate returns the Errors as an Aggregate, or nil if there are none.
+func (l ErrorList) ToAggregate() Aggregate {
+	if len(l) == 0 {
+		return nil
+	}
+	a := make(aggregate, len(l))
+	for i, err := range l {
+		a[i] = err
+	}
+	return a
+}
@@ -0,0 +1,56 @@
+// Package v1 is a minimal stand-in for the common ndd-runtime API types that
+// generated methods refer to.
+package v1
+
+// A ConditionKind represents a condition kind for a resource.
+type ConditionKind string
+
+// A Condition that may apply to a resource.
+type Condition struct {
+	Kind    ConditionKind
+	Status  string
+	Reason  string
+	Message string
+}
+
+// A DeletionPolicy determines what should happen to the underlying external
+// resource when a managed resource is deleted.
+type DeletionPolicy string
+
+// A Reference to a named object.
+type Reference struct {
+	Name string
+}
+
+// ResourceSpec defines the desired state of a managed resource.
+type ResourceSpec struct {
+	Active               bool
+	NetworkNodeReference *Reference
+	DeletionPolicy       DeletionPolicy
+}
+
+// A ConditionedStatus reflects the observed status of a resource.
+type ConditionedStatus struct {
+	Conditions []Condition
+}
+
+// SetConditions sets the supplied conditions.
+func (s *ConditionedStatus) SetConditions(c ...Condition) { s.Conditions = c }
+
+// GetCondition returns the condition of the supplied kind.
+func (s *ConditionedStatus) GetCondition(ck ConditionKind) Condition {
+	for _, c := range s.Conditions {
+		if c.Kind == ck {
+			return c
+		}
+	}
+	return Condition{Kind: ck}
+}
+
+// ResourceStatus represents the observed state of a managed resource.
+type ResourceStatus struct {
+	ConditionedStatus
+	Target           []string
+	ExternalLeafRefs []string
+	ResourceIndexes  map[string]string
+}
@@ -0,0 +1,3 @@
+module github.com/netw-device-driver/ndd-runtime
+
+go 1.16
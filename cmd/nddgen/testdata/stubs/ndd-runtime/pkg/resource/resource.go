@@ -0,0 +1,16 @@
+// Package resource is a minimal stand-in for the ndd-runtime resource
+// interfaces that generated methods refer to.
+package resource
+
+import nddv1 "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
+
+// A Managed resource.
+type Managed interface {
+	GetActive() bool
+	GetCondition(ck nddv1.ConditionKind) nddv1.Condition
+}
+
+// A ManagedList is a list of managed resources.
+type ManagedList interface {
+	GetItems() []Managed
+}
@@ -1,10 +1,19 @@
 module github.com/netw-device-driver/ndd-tools
 
-go 1.16
+go 1.24.0
 
 require (
 	github.com/dave/jennifer v1.4.1
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/cobra v1.2.1
-	golang.org/x/tools v0.1.5
+	golang.org/x/tools v0.40.0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
 )
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache records the inputs and outputs of previous generation runs so
+// that packages whose inputs have not changed need not be generated again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+// Filename of the cache file written at the module root.
+const Filename = ".ndd-gen.cache"
+
+// An Entry records the outputs generated from a package's inputs.
+type Entry struct {
+	// Inputs is a hash of everything that determines the generated outputs.
+	Inputs string `json:"inputs"`
+
+	// Outputs maps each generated file to a hash of its contents. Files that
+	// were not generated, for example because they would have contained no
+	// methods, map to an empty string; they must either not exist or not have
+	// been generated by ndd-gen.
+	Outputs map[string]string `json:"outputs"`
+}
+
+// A Cache of generation results, keyed by package path. It is safe for
+// concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Entry
+}
+
+// Load the cache file at the supplied path. A cache that does not exist is
+// treated as empty. A cache that cannot be parsed is an error; it may be
+// removed, in which case every package is generated again.
+func Load(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: map[string]Entry{}}
+	data, err := ioutil.ReadFile(path) // nolint:gosec
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read cache file")
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse cache file %s", path)
+	}
+	if c.entries == nil {
+		c.entries = map[string]Entry{}
+	}
+	return c, nil
+}
+
+// Save the cache to the file it was loaded from.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot encode cache")
+	}
+	return errors.Wrap(ioutil.WriteFile(c.path, data, 0644), "cannot write cache file") // nolint:gosec
+}
+
+// Fresh returns true if the supplied package was previously generated from
+// the supplied inputs, and its generated outputs have not since changed.
+func (c *Cache) Fresh(pkgPath, inputs string) bool {
+	c.mu.Lock()
+	e, ok := c.entries[pkgPath]
+	c.mu.Unlock()
+	if !ok || e.Inputs != inputs {
+		return false
+	}
+	for file, hash := range e.Outputs {
+		data, err := ioutil.ReadFile(file) // nolint:gosec
+		if hash == "" && (os.IsNotExist(err) || err == nil && !generate.IsGenerated(data)) {
+			continue
+		}
+		if err != nil || Hash(data) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// Put records the outputs generated for the supplied package from the
+// supplied inputs.
+func (c *Cache) Put(pkgPath, inputs string, outputs map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[pkgPath] = Entry{Inputs: inputs, Outputs: outputs}
+}
+
+// Hash returns a hex encoded SHA-256 hash of the supplied data.
+func Hash(data ...[]byte) string {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)         // nolint:errcheck
+		h.Write([]byte{0}) // nolint:errcheck
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Inputs returns a hash of the inputs of generating code for the supplied
+// package; the supplied extra data (e.g. version and configuration), the
+// contents of the package's Go files except those that are excluded (e.g.
+// because they are generated), and the exported API of all packages it
+// transitively imports from outside the standard library.
+func Inputs(p *packages.Package, exclude map[string]bool, extra ...[]byte) (string, error) {
+	data := append([][]byte{}, extra...)
+
+	files := append([]string{}, p.GoFiles...)
+	sort.Strings(files)
+	for _, file := range files {
+		if exclude[filepath.Base(file)] {
+			continue
+		}
+		content, err := ioutil.ReadFile(file) // nolint:gosec
+		if err != nil {
+			return "", errors.Wrap(err, "cannot read Go file")
+		}
+		data = append(data, []byte(filepath.Base(file)), content)
+	}
+
+	deps := map[string]*packages.Package{}
+	dependencies(p, deps)
+	paths := make([]string, 0, len(deps))
+	for path := range deps {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		data = append(data, []byte(path), api(deps[path].Types))
+	}
+
+	return Hash(data...), nil
+}
+
+// dependencies adds all packages transitively imported by the supplied
+// package from outside the standard library to the supplied map.
+func dependencies(p *packages.Package, deps map[string]*packages.Package) {
+	for path, i := range p.Imports {
+		if _, ok := deps[path]; ok || i.Module == nil || i.Types == nil {
+			continue
+		}
+		deps[path] = i
+		dependencies(i, deps)
+	}
+}
+
+// api returns a description of the exported objects of the supplied package,
+// including the methods of its named types.
+func api(p *types.Package) []byte {
+	var b []byte
+	for _, n := range p.Scope().Names() {
+		o := p.Scope().Lookup(n)
+		if !o.Exported() {
+			continue
+		}
+		b = append(b, types.ObjectString(o, nil)...)
+		b = append(b, '\n')
+		named, ok := o.Type().(*types.Named)
+		if _, isType := o.(*types.TypeName); !ok || !isType {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			b = append(b, types.ObjectString(named.Method(i), nil)...)
+			b = append(b, '\n')
+		}
+	}
+	return b
+}
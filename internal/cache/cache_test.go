@@ -0,0 +1,241 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+const generated = "// " + generate.HeaderGenerated + "\n\npackage v1\n"
+
+func write(t *testing.T, file, data string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil { // nolint:gosec
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("NotExist", func(t *testing.T) {
+		c, err := Load(filepath.Join(dir, "missing"))
+		if err != nil {
+			t.Fatalf("Load(...): %v", err)
+		}
+		if c.Fresh("example.org/v1", "in") {
+			t.Errorf("Fresh(...): want false for an empty cache")
+		}
+	})
+
+	t.Run("Unparsable", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid")
+		write(t, path, "<<<<<<< HEAD\n{}")
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(...): want error for an unparsable cache file")
+		}
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		path := filepath.Join(dir, "null")
+		write(t, path, "null")
+		c, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(...): %v", err)
+		}
+		c.Put("example.org/v1", "in", nil)
+		if !c.Fresh("example.org/v1", "in") {
+			t.Errorf("Fresh(...): want true after Put")
+		}
+	})
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		path := filepath.Join(dir, Filename)
+		out := filepath.Join(dir, "zz_generated.go")
+		write(t, out, generated)
+
+		c, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(...): %v", err)
+		}
+		c.Put("example.org/v1", "in", map[string]string{out: Hash([]byte(generated))})
+		if err := c.Save(); err != nil {
+			t.Fatalf("Save(): %v", err)
+		}
+
+		c, err = Load(path)
+		if err != nil {
+			t.Fatalf("Load(...): %v", err)
+		}
+		if !c.Fresh("example.org/v1", "in") {
+			t.Errorf("Fresh(...): want true for a saved entry")
+		}
+	})
+}
+
+func TestFresh(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "zz_generated.go")
+	none := filepath.Join(dir, "zz_generated.none.go")
+
+	cases := map[string]struct {
+		setup  func(t *testing.T)
+		inputs string
+		want   bool
+	}{
+		"Unchanged": {
+			inputs: "in",
+			want:   true,
+		},
+		"InputsChanged": {
+			inputs: "other",
+			want:   false,
+		},
+		"OutputModified": {
+			setup:  func(t *testing.T) { write(t, out, generated+"\nvar x int\n") },
+			inputs: "in",
+			want:   false,
+		},
+		"OutputRemoved": {
+			setup: func(t *testing.T) {
+				if err := os.Remove(out); err != nil {
+					t.Fatal(err)
+				}
+			},
+			inputs: "in",
+			want:   false,
+		},
+		"UngeneratedOutputHandWritten": {
+			setup:  func(t *testing.T) { write(t, none, "package v1\n") },
+			inputs: "in",
+			want:   true,
+		},
+		"UngeneratedOutputGenerated": {
+			setup:  func(t *testing.T) { write(t, none, generated) },
+			inputs: "in",
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			write(t, out, generated)
+			os.Remove(none) // nolint:errcheck
+			if tc.setup != nil {
+				tc.setup(t)
+			}
+
+			c, err := Load(filepath.Join(dir, Filename))
+			if err != nil {
+				t.Fatalf("Load(...): %v", err)
+			}
+			c.Put("example.org/v1", "in", map[string]string{out: Hash([]byte(generated)), none: ""})
+
+			if got := c.Fresh("example.org/v1", tc.inputs); got != tc.want {
+				t.Errorf("Fresh(...): want %t, got %t", tc.want, got)
+			}
+			if c.Fresh("example.org/v2", tc.inputs) {
+				t.Errorf("Fresh(...): want false for a package that was not Put")
+			}
+		})
+	}
+}
+
+// check type checks the supplied source as a package of a module.
+func check(t *testing.T, path, src string) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "dep.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := (&types.Config{}).Check(path, fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packages.Package{PkgPath: path, Types: p, Module: &packages.Module{Path: path}}
+}
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "types.go")
+	gen := filepath.Join(dir, "zz_generated.go")
+	exclude := map[string]bool{"zz_generated.go": true}
+
+	inputs := func(dep string) string {
+		t.Helper()
+		p := &packages.Package{
+			GoFiles: []string{src, gen},
+			Imports: map[string]*packages.Package{"example.org/dep": check(t, "example.org/dep", dep)},
+		}
+		h, err := Inputs(p, exclude, []byte("v1"))
+		if err != nil {
+			t.Fatalf("Inputs(...): %v", err)
+		}
+		return h
+	}
+
+	const dep = "package dep\n\nfunc F() int { return 0 }\n\nfunc f() {}\n"
+	write(t, src, "package v1\n")
+	write(t, gen, generated)
+	base := inputs(dep)
+
+	if got := inputs(dep); got != base {
+		t.Errorf("Inputs(...): want the same hash of the same inputs")
+	}
+
+	write(t, gen, generated+"\nvar x int\n")
+	if got := inputs(dep); got != base {
+		t.Errorf("Inputs(...): want the same hash when only an excluded file changed")
+	}
+
+	if got := inputs("package dep\n\nfunc F() int { return 1 }\n\nfunc f(int) {}\n"); got != base {
+		t.Errorf("Inputs(...): want the same hash when only unexported or unexposed parts of a dependency changed")
+	}
+
+	if got := inputs("package dep\n\nfunc F() string { return \"\" }\n\nfunc f() {}\n"); got == base {
+		t.Errorf("Inputs(...): want a different hash when the API of a dependency changed")
+	}
+
+	write(t, src, "package v1\n\ntype Thing struct{}\n")
+	if got := inputs(dep); got == base {
+		t.Errorf("Inputs(...): want a different hash when a source file changed")
+	}
+
+	p := &packages.Package{GoFiles: []string{src}}
+	a, err := Inputs(p, nil, []byte("v1"))
+	if err != nil {
+		t.Fatalf("Inputs(...): %v", err)
+	}
+	b, err := Inputs(p, nil, []byte("v2"))
+	if err != nil {
+		t.Fatalf("Inputs(...): %v", err)
+	}
+	if a == b {
+		t.Errorf("Inputs(...): want a different hash when the extra data changed")
+	}
+}
@@ -21,14 +21,48 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"sort"
 	"strings"
 
+	"github.com/pkg/errors"
 	"golang.org/x/tools/go/packages"
 )
 
 // DefaultMarkerPrefix that is commonly used by comment markers.
 const DefaultMarkerPrefix = "+"
 
+// Comment markers of struct fields that model YANG leaves.
+const (
+	// MarkerLeafRef marks a field whose value refers to the key leaf of a
+	// YANG list, e.g. +ndd:leafref=/interface/name.
+	MarkerLeafRef = "ndd:leafref"
+
+	// MarkerKey marks a field whose value is a key of the resource, e.g.
+	// +ndd:key.
+	MarkerKey = "ndd:key"
+
+	// MarkerRange restricts the value of a numeric field to one or more
+	// ranges, in the syntax of a YANG range statement, e.g.
+	// +ndd:validation:Range="1..10|20..max".
+	MarkerRange = "ndd:validation:Range"
+
+	// MarkerLength restricts the length of a string field to one or more
+	// ranges, in the syntax of a YANG length statement, e.g.
+	// +ndd:validation:Length="1..64".
+	MarkerLength = "ndd:validation:Length"
+
+	// MarkerDefault sets the default value of a field, per its YANG default
+	// statement, e.g. +ndd:default=1500. It is equivalent to, and overridden
+	// by, +kubebuilder:default.
+	MarkerDefault = "ndd:default"
+
+	// MarkerYangPath sets the YANG schema path of a field, relative to that of
+	// its struct, e.g. +ndd:yang:path=config/mtu. Absolute paths, e.g.
+	// +ndd:yang:path=/interface, are the root of a resource. Fields are
+	// otherwise at the path element named after their JSON name.
+	MarkerYangPath = "ndd:yang:path"
+)
+
 type fl struct {
 	Filename string
 	Line     int
@@ -38,19 +72,33 @@ type fl struct {
 type Comments struct {
 	groups map[fl]*ast.CommentGroup
 	fset   *token.FileSet
+
+	// code is the sorted positions at which the code of the package, i.e.
+	// anything but its comments, begins and ends.
+	code []token.Pos
 }
 
 // In returns all comments in a particular package.
 func In(p *packages.Package) Comments {
 	groups := map[fl]*ast.CommentGroup{}
+	var code []token.Pos
 
 	for _, f := range p.Syntax {
 		for _, g := range f.Comments {
 			p := p.Fset.Position(g.End())
 			groups[fl{Filename: p.Filename, Line: p.Line}] = g
 		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n.(type) {
+			case nil, *ast.File, *ast.CommentGroup, *ast.Comment:
+			default:
+				code = append(code, n.Pos(), n.End())
+			}
+			return true
+		})
 	}
-	return Comments{groups: groups, fset: p.Fset}
+	sort.Slice(code, func(i, j int) bool { return code[i] < code[j] })
+	return Comments{groups: groups, fset: p.Fset, code: code}
 }
 
 // For returns the comments for the supplied Object, if any.
@@ -59,6 +107,39 @@ func (c Comments) For(o types.Object) string {
 	return c.groups[fl{Filename: p.Filename, Line: p.Line - 1}].Text()
 }
 
+// Field returns the comments for the supplied struct field, if any. These are
+// its doc comment or, if it has none, the comment that trails it on the same
+// line. A doc comment must start on a line of its own and immediately precede
+// the field, so that the comment trailing the previous field is not mistaken
+// for it.
+func (c Comments) Field(f *types.Var) string {
+	p := c.fset.Position(f.Pos())
+	if g := c.groups[fl{Filename: p.Filename, Line: p.Line - 1}]; g != nil && !c.trails(g) && !c.hasCode(g.End(), f.Pos()) {
+		return g.Text()
+	}
+	g := c.groups[fl{Filename: p.Filename, Line: p.Line}]
+	if g == nil || g.Pos() < f.Pos() {
+		return ""
+	}
+	return g.Text()
+}
+
+// trails returns true if the supplied comment group starts on a line after
+// code, i.e. if it is a trailing comment.
+func (c Comments) trails(g *ast.CommentGroup) bool {
+	f := c.fset.File(g.Pos())
+	if f == nil {
+		return false
+	}
+	return c.hasCode(f.LineStart(f.Line(g.Pos())), g.Pos())
+}
+
+// hasCode returns true if any code begins or ends in [from, to).
+func (c Comments) hasCode(from, to token.Pos) bool {
+	i := sort.Search(len(c.code), func(i int) bool { return c.code[i] >= from })
+	return i < len(c.code) && c.code[i] < to
+}
+
 // Before returns the comments before the supplied Object, if any. A comment is
 // deemed to be 'before' (rather than 'for') an Object if it ends exactly one
 // blank line above where the Object (including its comment, if any) begins.
@@ -78,6 +159,33 @@ func (c Comments) Before(o types.Object) string {
 	return c.groups[fl{Filename: start.Filename, Line: start.Line - 2}].Text()
 }
 
+// Package returns the comments above the package clause of every file in the
+// supplied package, for example the package's doc comment and any comment
+// markers that apply to the whole package.
+func Package(p *packages.Package) string {
+	var b strings.Builder
+	for _, f := range p.Syntax {
+		for _, g := range f.Comments {
+			if g.End() >= f.Package {
+				break
+			}
+			b.WriteString(g.Text())
+		}
+	}
+	return b.String()
+}
+
+// PackageDoc returns the doc comment of the supplied package, i.e. the comment
+// immediately above the package clause of one of its files.
+func PackageDoc(p *packages.Package) string {
+	for _, f := range p.Syntax {
+		if f.Doc != nil {
+			return f.Doc.Text()
+		}
+	}
+	return ""
+}
+
 // Markers are comments that begin with a special character (typically
 // DefaultMarkerPrefix). Comment markers that contain '=' are considered to be
 // key=value pairs, represented as one map key with a slice of multiple values.
@@ -118,3 +226,128 @@ func ParseMarkersWithPrefix(prefix, comment string) Markers {
 
 	return m
 }
+
+// Keys returns the sorted keys of the markers.
+func (m Markers) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LeafRef returns the YANG list and key leaf referred to by a field marked
+// with MarkerLeafRef. For example +ndd:leafref=/interface/name refers to leaf
+// name of list /interface. An empty list is returned if the field is not
+// marked, and an error if its marker does not name a leaf of a list.
+func (m Markers) LeafRef() (list, key string, err error) {
+	v := m[MarkerLeafRef]
+	if len(v) == 0 {
+		return "", "", nil
+	}
+	path := Unquote(v[len(v)-1])
+	i := strings.LastIndex(path, "/")
+	if !strings.HasPrefix(path, "/") || i <= 0 || i == len(path)-1 {
+		return "", "", errors.Errorf("+%s=%s is not the path of a leaf of a list", MarkerLeafRef, path)
+	}
+	return path[:i], path[i+1:], nil
+}
+
+// YangPath returns the YANG schema path of a field marked with
+// MarkerYangPath, or an empty string if the field is not marked.
+func (m Markers) YangPath() string {
+	v := m[MarkerYangPath]
+	if len(v) == 0 {
+		return ""
+	}
+	return Unquote(v[len(v)-1])
+}
+
+// IsKey returns true if a field is marked with MarkerKey.
+func (m Markers) IsKey() bool {
+	return m[MarkerKey] != nil
+}
+
+// Args returns the arguments of every marker with the supplied key, for
+// example +kubebuilder:resource:scope=Cluster,categories={ndd,srl} would be
+// parsed as map[string]string{"scope": "Cluster", "categories": "{ndd,srl}"}.
+func (m Markers) Args(key string) []map[string]string {
+	var args []map[string]string
+	for _, k := range m.Keys() {
+		if k != key && !strings.HasPrefix(k, key+":") {
+			continue
+		}
+		for _, v := range m[k] {
+			raw := strings.TrimPrefix(strings.TrimPrefix(k, key), ":")
+			if raw != "" {
+				raw += "=" + v
+			}
+			args = append(args, parseArgs(raw))
+		}
+	}
+	return args
+}
+
+// parseArgs parses comma separated key=value pairs. Values may be quoted, or
+// be lists enclosed in braces, in which case they may contain commas.
+func parseArgs(s string) map[string]string {
+	args := map[string]string{}
+	var parts []string
+	depth, quote, start := 0, rune(0), 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, s[start:])
+	for _, p := range parts {
+		kv := strings.SplitN(p, "=", 2)
+		if strings.TrimSpace(kv[0]) == "" {
+			continue
+		}
+		v := ""
+		if len(kv) > 1 {
+			v = Unquote(strings.TrimSpace(kv[1]))
+		}
+		args[strings.TrimSpace(kv[0])] = v
+	}
+	return args
+}
+
+// List splits a marker value into a list. Lists are either separated by
+// semicolons, or enclosed in braces and separated by commas.
+func List(v string) []string {
+	sep := ";"
+	if strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}") {
+		v, sep = v[1:len(v)-1], ","
+	}
+	var l []string
+	for _, e := range strings.Split(v, sep) {
+		if e = Unquote(strings.TrimSpace(e)); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+// Unquote removes the double quotes or backticks enclosing the supplied value,
+// if any.
+func Unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '`') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
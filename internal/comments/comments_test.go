@@ -0,0 +1,74 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package comments
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const fieldsSrc = `package v1
+
+type Spec struct {
+	A int32 // +kubebuilder:validation:Minimum=1
+	B int32
+
+	// C is documented.
+	C int32
+	D int32 // +ndd:key
+	// E is documented.
+	E int32
+
+	// F is documented.
+	F int32; G int32
+}
+`
+
+func TestField(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "types.go", fieldsSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := (&types.Config{}).Check("example.org/v1", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := In(&packages.Package{Fset: fset, Syntax: []*ast.File{f}, Types: pkg})
+
+	want := map[string]string{
+		"A": "+kubebuilder:validation:Minimum=1\n",
+		"B": "",
+		"C": "C is documented.\n",
+		"D": "+ndd:key\n",
+		"E": "E is documented.\n",
+		"F": "F is documented.\n",
+		"G": "",
+	}
+	s := pkg.Scope().Lookup("Spec").Type().Underlying().(*types.Struct)
+	for i := 0; i < s.NumFields(); i++ {
+		fld := s.Field(i)
+		if got := c.Field(fld); got != want[fld.Name()] {
+			t.Errorf("Field(%s): want %q, got %q", fld.Name(), want[fld.Name()], got)
+		}
+	}
+}
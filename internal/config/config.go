@@ -0,0 +1,331 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads declarative ndd-gen configuration files.
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Filename of the configuration file discovered at the module root.
+const Filename = "ndd-gen.yaml"
+
+// Generator names.
+const (
+	GeneratorManaged              = "managed"
+	GeneratorManagedList          = "managed-list"
+	GeneratorNetworkNode          = "network-node"
+	GeneratorNetworkNodeUsage     = "network-node-usage"
+	GeneratorNetworkNodeUsageList = "network-node-usage-list"
+	GeneratorDeepCopy             = "deepcopy"
+	GeneratorCRDs                 = "crds"
+	GeneratorRBAC                 = "rbac"
+	GeneratorRegister             = "register"
+	GeneratorAssertions           = "assertions"
+	GeneratorDocs                 = "docs"
+	GeneratorSamples              = "samples"
+	GeneratorReferences           = "references"
+	GeneratorValidation           = "validation"
+	GeneratorDefaults             = "defaults"
+	GeneratorDiff                 = "diff"
+	GeneratorPaths                = "paths"
+	GeneratorFakes                = "fakes"
+)
+
+// An Import is a Go import path and the alias used to refer to it in
+// generated code.
+type Import struct {
+	Path  string `yaml:"path,omitempty"`
+	Alias string `yaml:"alias,omitempty"`
+}
+
+// Imports used by generated code.
+type Imports struct {
+	Core     Import `yaml:"core,omitempty"`
+	Runtime  Import `yaml:"runtime,omitempty"`
+	Resource Import `yaml:"resource,omitempty"`
+
+	// KubeRuntime is the Kubernetes API machinery runtime package, which
+	// defines runtime.Object.
+	KubeRuntime Import `yaml:"kubeRuntime,omitempty"`
+
+	// GNMI is the gNMI protobuf package, which defines gnmi.Path.
+	GNMI Import `yaml:"gnmi,omitempty"`
+}
+
+// A Generator configures a single generator.
+type Generator struct {
+	// Enabled determines whether the generator runs. Generators are enabled
+	// unless explicitly disabled.
+	Enabled *bool `yaml:"enabled,omitempty"`
+
+	// Filename of the files written by the generator.
+	Filename string `yaml:"filename,omitempty"`
+
+	// Dir into which the generator writes files, for generators that write
+	// files outside of the package they generate them for. Relative paths
+	// are resolved against the module root, except those of the fakes
+	// generator, which writes a subpackage of each package and so requires
+	// a relative path whose last element is a valid Go package name.
+	Dir string `yaml:"dir,omitempty"`
+
+	// Receiver name used by generated methods.
+	Receiver string `yaml:"receiver,omitempty"`
+
+	// Tests determines whether the generator also writes unit tests of the
+	// methods it generates, for generators that support it. Tests are not
+	// written unless explicitly enabled.
+	Tests *bool `yaml:"tests,omitempty"`
+
+	// Formats written by the generator, for generators that support several,
+	// e.g. markdown and html.
+	Formats []string `yaml:"formats,omitempty"`
+
+	// Group is the API group of the ndd-core kinds that generated files refer
+	// to, for generators that refer to them, e.g. the NetworkNode and
+	// NetworkNodeUsage kinds granted by the rbac generator.
+	Group string `yaml:"group,omitempty"`
+}
+
+// IsEnabled returns true unless the generator is explicitly disabled.
+func (g Generator) IsEnabled() bool {
+	return g.Enabled == nil || *g.Enabled
+}
+
+// WithTests returns true if the generator should write unit tests.
+func (g Generator) WithTests() bool {
+	return g.Tests != nil && *g.Tests
+}
+
+// A Package overrides the configuration of the packages matching its Path. A
+// Path ending in /... matches the package and all packages beneath it.
+type Package struct {
+	Path       string               `yaml:"path"`
+	Imports    Imports              `yaml:"imports,omitempty"`
+	Generators map[string]Generator `yaml:"generators,omitempty"`
+}
+
+// A Config declares how ndd-gen generates code for a module.
+type Config struct {
+	// HeaderFile whose contents are added to the top of all generated files.
+	// Relative paths are resolved against the directory of the config file.
+	HeaderFile string `yaml:"headerFile,omitempty"`
+
+	// Paths of the packages for which to generate code.
+	Paths []string `yaml:"paths,omitempty"`
+
+	Imports    Imports              `yaml:"imports,omitempty"`
+	Generators map[string]Generator `yaml:"generators,omitempty"`
+	Packages   []Package            `yaml:"packages,omitempty"`
+}
+
+// Default returns the configuration used when no configuration file exists.
+func Default() *Config {
+	return &Config{
+		Imports: Imports{
+			Core:        Import{Path: "k8s.io/api/core/v1", Alias: "corev1"},
+			Runtime:     Import{Path: "github.com/netw-device-driver/ndd-runtime/apis/common/v1", Alias: "nddv1"},
+			Resource:    Import{Path: "github.com/netw-device-driver/ndd-runtime/pkg/resource", Alias: "resource"},
+			KubeRuntime: Import{Path: "k8s.io/apimachinery/pkg/runtime", Alias: "runtime"},
+			GNMI:        Import{Path: "github.com/openconfig/gnmi/proto/gnmi", Alias: "gnmi"},
+		},
+		Generators: map[string]Generator{
+			GeneratorManaged:              {Filename: "zz_generated.managed.go", Receiver: "mg"},
+			GeneratorManagedList:          {Filename: "zz_generated.managedlist.go", Receiver: "l"},
+			GeneratorNetworkNode:          {Filename: "zz_generated.nn.go", Receiver: "p"},
+			GeneratorNetworkNodeUsage:     {Filename: "zz_generated.nnu.go", Receiver: "p"},
+			GeneratorNetworkNodeUsageList: {Filename: "zz_generated.nnulist.go", Receiver: "p"},
+			GeneratorDeepCopy:             {Filename: "zz_generated.deepcopy.go", Receiver: "in"},
+			GeneratorCRDs:                 {Dir: "package/crds"},
+			GeneratorRBAC:                 {Dir: "package/rbac", Group: "dvr.ndd.henderiw.be"},
+			GeneratorRegister:             {Filename: "zz_generated.register.go"},
+			GeneratorAssertions:           {Filename: "zz_generated.assertions.go"},
+			GeneratorDocs:                 {Dir: "docs/api", Formats: []string{"markdown"}},
+			GeneratorSamples:              {Dir: "examples"},
+			GeneratorReferences:           {Filename: "zz_generated.references.go", Receiver: "mg"},
+			GeneratorValidation:           {Filename: "zz_generated.validate.go", Receiver: "s"},
+			GeneratorDefaults:             {Filename: "zz_generated.defaults.go", Receiver: "mg"},
+			GeneratorDiff:                 {Filename: "zz_generated.diff.go", Receiver: "s"},
+			GeneratorPaths:                {Filename: "zz_generated.paths.go"},
+			GeneratorFakes:                {Filename: "zz_generated.fake.go", Dir: "fake"},
+		},
+	}
+}
+
+// Load the configuration file at the supplied path. Settings omitted from the
+// file take their Default values.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config file")
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse config file %s", path)
+	}
+	if c.HeaderFile != "" && !filepath.IsAbs(c.HeaderFile) {
+		c.HeaderFile = filepath.Join(filepath.Dir(path), c.HeaderFile)
+	}
+	return Default().merge(c), nil
+}
+
+// Discover returns the path of the configuration file at the root of the
+// module containing the supplied directory, or an empty string if there is
+// none.
+func Discover(dir string) (string, error) {
+	root, err := ModuleRoot(dir)
+	if err != nil || root == "" {
+		return "", err
+	}
+	path := filepath.Join(root, Filename)
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+	return path, nil
+}
+
+// ModuleRoot returns the root directory of the module containing the supplied
+// directory, i.e. the closest directory containing a go.mod file, or an empty
+// string if there is none.
+func ModuleRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot determine absolute path")
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// For returns the configuration that applies to the package with the supplied
+// import path, i.e. the configuration with all matching package overrides
+// applied in order.
+func (c *Config) For(pkgPath string) *Config {
+	out := c.merge(&Config{})
+	for _, p := range c.Packages {
+		if !matches(p.Path, pkgPath) {
+			continue
+		}
+		out = out.merge(&Config{Imports: p.Imports, Generators: p.Generators})
+	}
+	out.Packages = nil
+	return out
+}
+
+// SetDefaults sets the default configuration of the named generator. Settings
+// of the configuration that are not empty override them.
+func (c *Config) SetDefaults(name string, g Generator) {
+	if c.Generators == nil {
+		c.Generators = map[string]Generator{}
+	}
+	c.Generators[name] = g.merge(c.Generators[name])
+}
+
+// Generator returns the configuration of the named generator.
+func (c *Config) Generator(name string) Generator {
+	return c.Generators[name]
+}
+
+func matches(pattern, pkgPath string) bool {
+	if strings.HasSuffix(pattern, "/...") {
+		prefix := strings.TrimSuffix(pattern, "/...")
+		return pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/")
+	}
+	return pattern == pkgPath
+}
+
+// merge returns a copy of c with all settings of o that are not empty applied.
+func (c *Config) merge(o *Config) *Config {
+	out := &Config{
+		HeaderFile: c.HeaderFile,
+		Paths:      c.Paths,
+		Imports:    c.Imports.merge(o.Imports),
+		Generators: make(map[string]Generator, len(c.Generators)),
+		Packages:   append(append([]Package{}, c.Packages...), o.Packages...),
+	}
+	if o.HeaderFile != "" {
+		out.HeaderFile = o.HeaderFile
+	}
+	if len(o.Paths) > 0 {
+		out.Paths = o.Paths
+	}
+	for name, g := range c.Generators {
+		out.Generators[name] = g
+	}
+	for name, g := range o.Generators {
+		out.Generators[name] = out.Generators[name].merge(g)
+	}
+	return out
+}
+
+func (i Imports) merge(o Imports) Imports {
+	return Imports{
+		Core:        i.Core.merge(o.Core),
+		Runtime:     i.Runtime.merge(o.Runtime),
+		Resource:    i.Resource.merge(o.Resource),
+		KubeRuntime: i.KubeRuntime.merge(o.KubeRuntime),
+		GNMI:        i.GNMI.merge(o.GNMI),
+	}
+}
+
+func (i Import) merge(o Import) Import {
+	if o.Path != "" {
+		i.Path = o.Path
+	}
+	if o.Alias != "" {
+		i.Alias = o.Alias
+	}
+	return i
+}
+
+func (g Generator) merge(o Generator) Generator {
+	if o.Enabled != nil {
+		g.Enabled = o.Enabled
+	}
+	if o.Filename != "" {
+		g.Filename = o.Filename
+	}
+	if o.Dir != "" {
+		g.Dir = o.Dir
+	}
+	if o.Receiver != "" {
+		g.Receiver = o.Receiver
+	}
+	if o.Tests != nil {
+		g.Tests = o.Tests
+	}
+	if len(o.Formats) > 0 {
+		g.Formats = o.Formats
+	}
+	if o.Group != "" {
+		g.Group = o.Group
+	}
+	return g
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crd generates Kubernetes CustomResourceDefinitions, including their
+// structural OpenAPI v3 schemas, from Go types.
+package crd
+
+import (
+	"bytes"
+	"go/types"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+// Marker keys.
+const (
+	MarkerGroupName   = "groupName"
+	MarkerVersionName = "versionName"
+	MarkerResource    = "kubebuilder:resource"
+	MarkerPrintColumn = "kubebuilder:printcolumn"
+	MarkerStatus      = "kubebuilder:subresource:status"
+)
+
+// Scopes of a CustomResourceDefinition.
+const (
+	ScopeCluster    = "Cluster"
+	ScopeNamespaced = "Namespaced"
+)
+
+// A CustomResourceDefinition of the apiextensions.k8s.io/v1 API. Only the
+// fields ndd-gen generates are included.
+type CustomResourceDefinition struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   Metadata `yaml:"metadata"`
+	Spec       Spec     `yaml:"spec"`
+}
+
+// Metadata of a CustomResourceDefinition.
+type Metadata struct {
+	Name string `yaml:"name"`
+}
+
+// Spec of a CustomResourceDefinition.
+type Spec struct {
+	Group    string    `yaml:"group"`
+	Names    Names     `yaml:"names"`
+	Scope    string    `yaml:"scope"`
+	Versions []Version `yaml:"versions"`
+}
+
+// Names of a CustomResourceDefinition.
+type Names struct {
+	Categories []string `yaml:"categories,omitempty"`
+	Kind       string   `yaml:"kind"`
+	ListKind   string   `yaml:"listKind"`
+	Plural     string   `yaml:"plural"`
+	ShortNames []string `yaml:"shortNames,omitempty"`
+	Singular   string   `yaml:"singular"`
+}
+
+// A Version of a CustomResourceDefinition.
+type Version struct {
+	AdditionalPrinterColumns []PrinterColumn `yaml:"additionalPrinterColumns,omitempty"`
+	Name                     string          `yaml:"name"`
+	Schema                   Validation      `yaml:"schema"`
+	Served                   bool            `yaml:"served"`
+	Storage                  bool            `yaml:"storage"`
+	Subresources             *Subresources   `yaml:"subresources,omitempty"`
+}
+
+// A PrinterColumn is an additional column printed by kubectl get.
+type PrinterColumn struct {
+	Description string `yaml:"description,omitempty"`
+	Format      string `yaml:"format,omitempty"`
+	JSONPath    string `yaml:"jsonPath"`
+	Name        string `yaml:"name"`
+	Priority    int32  `yaml:"priority,omitempty"`
+	Type        string `yaml:"type"`
+}
+
+// Validation of a Version.
+type Validation struct {
+	OpenAPIV3Schema *Schema `yaml:"openAPIV3Schema"`
+}
+
+// Subresources of a Version.
+type Subresources struct {
+	Status *StatusSubresource `yaml:"status,omitempty"`
+}
+
+// A StatusSubresource enables the status subresource.
+type StatusSubresource struct{}
+
+// Marshal the supplied CustomResourceDefinition to YAML, carrying
+// generate.HeaderGenerated.
+func Marshal(c *CustomResourceDefinition) ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal CustomResourceDefinition")
+	}
+	b := &bytes.Buffer{}
+	b.WriteString("# " + generate.HeaderGenerated + "\n---\n")
+	b.Write(data)
+	return b.Bytes(), nil
+}
+
+// A Builder builds CustomResourceDefinitions for the named types of a package.
+type Builder struct {
+	schemas  *Schemas
+	comments comments.Comments
+	group    string
+	version  string
+}
+
+// NewBuilder returns a Builder for the named types of the supplied package,
+// which must declare its API group. See GroupVersion.
+func NewBuilder(p *packages.Package) (*Builder, error) {
+	group, version, err := GroupVersion(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		schemas:  NewSchemas(p),
+		comments: comments.In(p),
+		group:    group,
+		version:  version,
+	}, nil
+}
+
+// GroupVersion returns the API group and version of the supplied package. The
+// package must declare its API group using the MarkerGroupName marker. Its API
+// version is its name, unless overridden by the MarkerVersionName marker.
+func GroupVersion(p *packages.Package) (group, version string, err error) {
+	m := comments.ParseMarkers(comments.Package(p))
+	group, version = last(m[MarkerGroupName]), last(m[MarkerVersionName])
+	if group == "" {
+		return "", "", errors.Errorf("package %s has no +%s marker", p.PkgPath, MarkerGroupName)
+	}
+	if version == "" {
+		version = p.Name
+	}
+	return group, version, nil
+}
+
+// Group returns the API group of the package.
+func (b *Builder) Group() string { return b.group }
+
+// Version returns the API version of the package.
+func (b *Builder) Version() string { return b.version }
+
+// Build returns the CustomResourceDefinition of the supplied Object, which
+// must be a named struct type. The supplied printer columns are used unless
+// the Object declares its own using the MarkerPrintColumn marker. Kinds are
+// cluster scoped unless the MarkerResource marker specifies otherwise.
+func (b *Builder) Build(o types.Object, columns []PrinterColumn) (*CustomResourceDefinition, error) {
+	if _, ok := o.Type().Underlying().(*types.Struct); !ok {
+		return nil, errors.Errorf("%s is not a struct", o.Name())
+	}
+	m := comments.ParseMarkers(b.comments.For(o) + "\n" + b.comments.Before(o))
+
+	kind := o.Name()
+	names, scope := NamesOf(m, kind)
+
+	if pcs, err := printColumns(m); err != nil {
+		return nil, errors.Wrapf(err, "invalid +%s marker of %s", MarkerPrintColumn, kind)
+	} else if len(pcs) > 0 {
+		columns = pcs
+	}
+
+	s, err := b.schemas.For(o.Type())
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build schema of %s", kind)
+	}
+	s.Description = Description(b.comments.For(o))
+
+	v := Version{
+		AdditionalPrinterColumns: columns,
+		Name:                     b.version,
+		Schema:                   Validation{OpenAPIV3Schema: s},
+		Served:                   true,
+		Storage:                  true,
+	}
+	if _, ok := s.Properties["status"]; ok || m[MarkerStatus] != nil {
+		v.Subresources = &Subresources{Status: &StatusSubresource{}}
+	}
+
+	return &CustomResourceDefinition{
+		APIVersion: "apiextensions.k8s.io/v1",
+		Kind:       "CustomResourceDefinition",
+		Metadata:   Metadata{Name: names.Plural + "." + b.group},
+		Spec: Spec{
+			Group:    b.group,
+			Names:    names,
+			Scope:    scope,
+			Versions: []Version{v},
+		},
+	}, nil
+}
+
+// NamesOf returns the names and scope of the supplied kind, as declared by the
+// supplied markers of its type using the MarkerResource marker.
+func NamesOf(m comments.Markers, kind string) (Names, string) {
+	names := Names{
+		Kind:     kind,
+		ListKind: kind + "List",
+		Plural:   Plural(strings.ToLower(kind)),
+		Singular: strings.ToLower(kind),
+	}
+	scope := ScopeCluster
+	for _, args := range m.Args(MarkerResource) {
+		for k, v := range args {
+			switch k {
+			case "path":
+				names.Plural = v
+			case "singular":
+				names.Singular = v
+			case "scope":
+				scope = v
+			case "shortName":
+				names.ShortNames = append(names.ShortNames, comments.List(v)...)
+			case "categories":
+				names.Categories = append(names.Categories, comments.List(v)...)
+			}
+		}
+	}
+	return names, scope
+}
+
+// Plural returns the plural of the supplied lower case singular noun.
+func Plural(s string) string {
+	switch {
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsAny(s[len(s)-2:len(s)-1], "aeiou"):
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
+
+func printColumns(m comments.Markers) ([]PrinterColumn, error) {
+	var pcs []PrinterColumn
+	for _, args := range m.Args(MarkerPrintColumn) {
+		pc := PrinterColumn{
+			Name:        args["name"],
+			Type:        args["type"],
+			JSONPath:    args["JSONPath"],
+			Description: args["description"],
+			Format:      args["format"],
+		}
+		if p, ok := args["priority"]; ok {
+			n, err := parseInt(p)
+			if err != nil {
+				return nil, err
+			}
+			pc.Priority = int32(n)
+		}
+		if pc.Name == "" || pc.Type == "" || pc.JSONPath == "" {
+			return nil, errors.New("name, type and JSONPath are required")
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs, nil
+}
+
+func last(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[len(s)-1]
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+)
+
+// applyMarkers applies the validation markers of the supplied comment markers
+// to the supplied schema, e.g. +kubebuilder:validation:MaxLength=64.
+func applyMarkers(s *Schema, m comments.Markers) error {
+	for _, k := range m.Keys() {
+		for _, v := range m[k] {
+			if err := applyMarker(s, k, comments.Unquote(v)); err != nil {
+				return errors.Wrapf(err, "+%s=%s", k, v)
+			}
+		}
+	}
+	return nil
+}
+
+func applyMarker(s *Schema, k, v string) error { // nolint:gocyclo
+	var err error
+	switch k {
+	case MarkerPreserveUnknown:
+		s.XPreserveUnknownFields = true
+	case MarkerEmbeddedResource:
+		s.XEmbeddedResource = true
+	case MarkerNullable:
+		s.Nullable = true
+	case MarkerListType:
+		s.XListType = v
+	case MarkerListMapKey:
+		s.XListMapKeys = append(s.XListMapKeys, v)
+	case MarkerMapType:
+		s.XMapType = v
+	case MarkerDefault:
+		s.Default, err = defaulted(s, v)
+		return err
+	case comments.MarkerDefault:
+		if s.Default == nil {
+			s.Default, err = defaulted(s, v)
+		}
+		return err
+	}
+	if !strings.HasPrefix(k, MarkerValidationPrefix) {
+		return nil
+	}
+
+	switch strings.TrimPrefix(k, MarkerValidationPrefix) {
+	case "Enum":
+		s.Enum = nil
+		for _, e := range comments.List(v) {
+			val, err := typed(s.Type, e)
+			if err != nil {
+				return err
+			}
+			s.Enum = append(s.Enum, val)
+		}
+	case "Minimum":
+		s.Minimum, err = parseFloat(v)
+	case "Maximum":
+		s.Maximum, err = parseFloat(v)
+	case "ExclusiveMinimum":
+		s.ExclusiveMinimum, err = strconv.ParseBool(v)
+	case "ExclusiveMaximum":
+		s.ExclusiveMaximum, err = strconv.ParseBool(v)
+	case "MultipleOf":
+		s.MultipleOf, err = parseFloat(v)
+	case "MinLength":
+		s.MinLength, err = parseIntPtr(v)
+	case "MaxLength":
+		s.MaxLength, err = parseIntPtr(v)
+	case "MinItems":
+		s.MinItems, err = parseIntPtr(v)
+	case "MaxItems":
+		s.MaxItems, err = parseIntPtr(v)
+	case "UniqueItems":
+		s.UniqueItems, err = strconv.ParseBool(v)
+	case "Pattern":
+		s.Pattern = v
+	case "Format":
+		s.Format = v
+	case "Type":
+		s.Type = v
+	}
+	return err
+}
+
+// typed returns the supplied value as the supplied schema type.
+func typed(t, v string) (interface{}, error) {
+	switch t {
+	case "integer":
+		return parseInt(v)
+	case "number":
+		f, err := parseFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		return *f, nil
+	case "boolean":
+		return strconv.ParseBool(v)
+	}
+	return v, nil
+}
+
+// defaulted returns the supplied default value as a value of the supplied
+// schema. Lists are written {a,b} or a;b and objects as YAML, e.g. {a: b}.
+func defaulted(s *Schema, v string) (interface{}, error) {
+	switch s.Type {
+	case "array":
+		l := []interface{}{}
+		for _, e := range comments.List(v) {
+			t := ""
+			if s.Items != nil {
+				t = s.Items.Type
+			}
+			val, err := typed(t, e)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, val)
+		}
+		return l, nil
+	case "object":
+		o := map[string]interface{}{}
+		return o, errors.Wrapf(yaml.Unmarshal([]byte(v), &o), "%q is not an object", v)
+	}
+	return typed(s.Type, v)
+}
+
+func parseInt(v string) (int64, error) {
+	i, err := strconv.ParseInt(v, 10, 64)
+	return i, errors.Wrapf(err, "%q is not an integer", v)
+}
+
+func parseIntPtr(v string) (*int64, error) {
+	i, err := parseInt(v)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func parseFloat(v string) (*float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%q is not a number", v)
+	}
+	return &f, nil
+}
@@ -0,0 +1,403 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+)
+
+// Marker keys.
+const (
+	MarkerOptional         = "optional"
+	MarkerValidationPrefix = "kubebuilder:validation:"
+	MarkerFieldOptional    = MarkerValidationPrefix + "Optional"
+	MarkerFieldRequired    = MarkerValidationPrefix + "Required"
+	MarkerPreserveUnknown  = "kubebuilder:pruning:PreserveUnknownFields"
+	MarkerEmbeddedResource = MarkerValidationPrefix + "EmbeddedResource"
+	MarkerNullable         = "nullable"
+	MarkerListType         = "listType"
+	MarkerListMapKey       = "listMapKey"
+	MarkerMapType          = "mapType"
+	MarkerDefault          = "kubebuilder:default"
+)
+
+// A Schema is a structural OpenAPI v3 schema, as used by the apiextensions.k8s.io/v1 API.
+type Schema struct {
+	Description          string             `yaml:"description,omitempty"`
+	Type                 string             `yaml:"type,omitempty"`
+	Format               string             `yaml:"format,omitempty"`
+	Default              interface{}        `yaml:"default,omitempty"`
+	Enum                 []interface{}      `yaml:"enum,omitempty"`
+	Maximum              *float64           `yaml:"maximum,omitempty"`
+	ExclusiveMaximum     bool               `yaml:"exclusiveMaximum,omitempty"`
+	Minimum              *float64           `yaml:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `yaml:"exclusiveMinimum,omitempty"`
+	MultipleOf           *float64           `yaml:"multipleOf,omitempty"`
+	MaxLength            *int64             `yaml:"maxLength,omitempty"`
+	MinLength            *int64             `yaml:"minLength,omitempty"`
+	Pattern              string             `yaml:"pattern,omitempty"`
+	MaxItems             *int64             `yaml:"maxItems,omitempty"`
+	MinItems             *int64             `yaml:"minItems,omitempty"`
+	UniqueItems          bool               `yaml:"uniqueItems,omitempty"`
+	Nullable             bool               `yaml:"nullable,omitempty"`
+	AnyOf                []*Schema          `yaml:"anyOf,omitempty"`
+	Items                *Schema            `yaml:"items,omitempty"`
+	Properties           map[string]*Schema `yaml:"properties,omitempty"`
+	Required             []string           `yaml:"required,omitempty"`
+	AdditionalProperties *Schema            `yaml:"additionalProperties,omitempty"`
+
+	XPreserveUnknownFields bool     `yaml:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	XEmbeddedResource      bool     `yaml:"x-kubernetes-embedded-resource,omitempty"`
+	XIntOrString           bool     `yaml:"x-kubernetes-int-or-string,omitempty"`
+	XListType              string   `yaml:"x-kubernetes-list-type,omitempty"`
+	XListMapKeys           []string `yaml:"x-kubernetes-list-map-keys,omitempty"`
+	XMapType               string   `yaml:"x-kubernetes-map-type,omitempty"`
+}
+
+// known returns the schemas of well known types that are not serialized as
+// their Go type suggests, keyed by their qualified type name.
+func known() map[string]func() *Schema {
+	intOrString := func() *Schema {
+		return &Schema{XIntOrString: true, AnyOf: []*Schema{{Type: "integer"}, {Type: "string"}}}
+	}
+	return map[string]func() *Schema{
+		"k8s.io/apimachinery/pkg/apis/meta/v1.Time":       func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+		"k8s.io/apimachinery/pkg/apis/meta/v1.MicroTime":  func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+		"k8s.io/apimachinery/pkg/apis/meta/v1.Duration":   func() *Schema { return &Schema{Type: "string"} },
+		"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta": func() *Schema { return &Schema{Type: "object"} },
+		"k8s.io/apimachinery/pkg/api/resource.Quantity":   intOrString,
+		"k8s.io/apimachinery/pkg/util/intstr.IntOrString": intOrString,
+		"k8s.io/apimachinery/pkg/runtime.RawExtension": func() *Schema {
+			return &Schema{Type: "object", XPreserveUnknownFields: true}
+		},
+		"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1.JSON": func() *Schema {
+			return &Schema{XPreserveUnknownFields: true}
+		},
+	}
+}
+
+// Schemas builds structural schemas for the types used by a package.
+type Schemas struct {
+	packages map[string]*packages.Package
+	comments map[string]comments.Comments
+	optional map[string]bool
+	known    map[string]func() *Schema
+	visiting map[*types.TypeName]bool
+}
+
+// NewSchemas returns Schemas for the types used by the supplied package. The
+// comments of the package and the packages it imports are used to document
+// the schemas, and to read their validation markers.
+func NewSchemas(p *packages.Package) *Schemas {
+	s := &Schemas{
+		packages: map[string]*packages.Package{},
+		comments: map[string]comments.Comments{},
+		optional: map[string]bool{},
+		known:    known(),
+		visiting: map[*types.TypeName]bool{},
+	}
+	var index func(p *packages.Package)
+	index = func(p *packages.Package) {
+		if _, ok := s.packages[p.PkgPath]; ok {
+			return
+		}
+		s.packages[p.PkgPath] = p
+		for _, i := range p.Imports {
+			index(i)
+		}
+	}
+	index(p)
+	return s
+}
+
+// Known returns true if the supplied named type has a well known schema, for
+// example metav1.Time, rather than one derived from its underlying type.
+func (s *Schemas) Known(t *types.Named) bool {
+	_, ok := s.known[qualifiedName(t.Obj())]
+	return ok
+}
+
+// For returns the schema of the supplied type.
+func (s *Schemas) For(t types.Type) (*Schema, error) {
+	switch t := t.(type) {
+	case *types.Named:
+		if fn, ok := s.known[qualifiedName(t.Obj())]; ok {
+			return fn(), nil
+		}
+		if s.visiting[t.Obj()] {
+			// Recursive types cannot be expressed by a structural schema.
+			return &Schema{Type: "object", XPreserveUnknownFields: true}, nil
+		}
+		s.visiting[t.Obj()] = true
+		defer delete(s.visiting, t.Obj())
+		sch, err := s.For(t.Underlying())
+		if err != nil {
+			return nil, err
+		}
+		return sch, errors.Wrapf(applyMarkers(sch, s.markers(t.Obj())), "invalid marker of %s", t.Obj().Name())
+
+	case *types.Basic:
+		return basic(t)
+
+	case *types.Pointer:
+		return s.For(t.Elem())
+
+	case *types.Slice:
+		return s.array(t.Elem())
+
+	case *types.Array:
+		return s.array(t.Elem())
+
+	case *types.Map:
+		if k, ok := t.Key().Underlying().(*types.Basic); !ok || k.Info()&types.IsString == 0 {
+			return nil, errors.Errorf("map key %s is not a string", t.Key())
+		}
+		v, err := s.For(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: v}, nil
+
+	case *types.Interface:
+		return &Schema{XPreserveUnknownFields: true}, nil
+
+	case *types.Struct:
+		return s.object(t)
+	}
+	return nil, errors.Errorf("unsupported type %s", t)
+}
+
+func (s *Schemas) array(elem types.Type) (*Schema, error) {
+	if b, ok := elem.(*types.Basic); ok && b.Kind() == types.Byte {
+		return &Schema{Type: "string", Format: "byte"}, nil
+	}
+	items, err := s.For(elem)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Type: "array", Items: items}, nil
+}
+
+func (s *Schemas) object(t *types.Struct) (*Schema, error) {
+	sch := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, f := range s.Fields(t) {
+		if f.Inline {
+			in, err := s.For(f.Var.Type())
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %s", f.Var.Name())
+			}
+			for n, p := range in.Properties {
+				sch.Properties[n] = p
+			}
+			sch.Required = append(sch.Required, in.Required...)
+			continue
+		}
+
+		fs, err := s.For(f.Var.Type())
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", f.Var.Name())
+		}
+		if err := applyMarkers(fs, f.Markers); err != nil {
+			return nil, errors.Wrapf(err, "invalid marker of field %s", f.Var.Name())
+		}
+		fs.Description = f.Description
+		sch.Properties[f.Name] = fs
+
+		if f.Required {
+			sch.Required = append(sch.Required, f.Name)
+		}
+	}
+	if len(sch.Properties) == 0 {
+		sch.Properties = nil
+	}
+	return sch, nil
+}
+
+// A Field of a struct, as it appears in the struct's schema.
+type Field struct {
+	Var *types.Var
+
+	// Name of the field's property, per its JSON tag.
+	Name string
+
+	// Inline fields contribute their properties to the struct's schema,
+	// rather than appearing as a property.
+	Inline bool
+
+	// Required fields must be specified.
+	Required bool
+
+	// Description of the field, from its comments or else those of its type.
+	Description string
+
+	// Markers of the field.
+	Markers comments.Markers
+}
+
+// Fields returns the fields of the supplied struct that appear in its schema,
+// in the order they are declared.
+func (s *Schemas) Fields(t *types.Struct) []Field {
+	fields := make([]Field, 0, t.NumFields())
+	for i := 0; i < t.NumFields(); i++ {
+		v := t.Field(i)
+		name, opts := jsonTag(reflect.StructTag(t.Tag(i)).Get("json"))
+		if name == "-" || (!v.Exported() && !v.Embedded()) {
+			continue
+		}
+		if opts["inline"] || (v.Embedded() && name == "") {
+			fields = append(fields, Field{Var: v, Inline: true})
+			continue
+		}
+		if name == "" {
+			name = v.Name()
+		}
+		f := Field{Var: v, Name: name, Markers: s.markers(v), Description: Description(s.Comments(v))}
+		if f.Description == "" {
+			if n, ok := derefNamed(v.Type()); ok {
+				f.Description = Description(s.Comments(n.Obj()))
+			}
+		}
+		f.Required = s.required(v, opts, f.Markers)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// required returns true if the supplied field is required. Fields are
+// required unless they are pointers, are omitted when empty, are marked as
+// optional, or are declared by a package that is marked as optional.
+func (s *Schemas) required(f *types.Var, opts map[string]bool, m comments.Markers) bool {
+	switch {
+	case m[MarkerFieldRequired] != nil:
+		return true
+	case m[MarkerOptional] != nil, m[MarkerFieldOptional] != nil:
+		return false
+	case opts["omitempty"]:
+		return false
+	}
+	if _, ok := f.Type().(*types.Pointer); ok {
+		return false
+	}
+	return f.Pkg() == nil || !s.packageOptional(f.Pkg().Path())
+}
+
+func (s *Schemas) packageOptional(path string) bool {
+	if o, ok := s.optional[path]; ok {
+		return o
+	}
+	o := false
+	if p, ok := s.packages[path]; ok {
+		o = comments.ParseMarkers(comments.Package(p))[MarkerFieldOptional] != nil
+	}
+	s.optional[path] = o
+	return o
+}
+
+// markers returns the comment markers of the supplied Object.
+func (s *Schemas) markers(o types.Object) comments.Markers {
+	return comments.ParseMarkers(s.Comments(o))
+}
+
+// Comments returns the comments of the supplied Object, which may be declared
+// by any package known to the Schemas. The comments of struct fields include
+// those trailing them.
+func (s *Schemas) Comments(o types.Object) string {
+	if o.Pkg() == nil {
+		return ""
+	}
+	c, ok := s.comments[o.Pkg().Path()]
+	if !ok {
+		p, known := s.packages[o.Pkg().Path()]
+		if !known {
+			return ""
+		}
+		c = comments.In(p)
+		s.comments[o.Pkg().Path()] = c
+	}
+	if v, ok := o.(*types.Var); ok && v.IsField() {
+		return c.Field(v)
+	}
+	return c.For(o)
+}
+
+func basic(t *types.Basic) (*Schema, error) {
+	i := t.Info()
+	switch {
+	case i&types.IsString != 0:
+		return &Schema{Type: "string"}, nil
+	case i&types.IsBoolean != 0:
+		return &Schema{Type: "boolean"}, nil
+	case i&types.IsInteger != 0:
+		// A uint32 does not fit in an int32, so it is formatted as an int64
+		// as controller-gen does.
+		switch t.Kind() {
+		case types.Int32:
+			return &Schema{Type: "integer", Format: "int32"}, nil
+		case types.Int64, types.Uint32, types.Uint64:
+			return &Schema{Type: "integer", Format: "int64"}, nil
+		}
+		return &Schema{Type: "integer"}, nil
+	case i&types.IsFloat != 0:
+		return &Schema{Type: "number"}, nil
+	}
+	return nil, errors.Errorf("unsupported type %s", t)
+}
+
+// Description returns the supplied comment without its comment markers,
+// joined into a single line.
+func Description(comment string) string {
+	lines := []string{}
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, comments.DefaultMarkerPrefix) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " ")
+}
+
+func jsonTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+func derefNamed(t types.Type) (*types.Named, bool) {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	n, ok := t.(*types.Named)
+	return n, ok
+}
+
+func qualifiedName(o types.Object) string {
+	if o.Pkg() == nil {
+		return o.Name()
+	}
+	return o.Pkg().Path() + "." + o.Name()
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"go/types"
+	"testing"
+)
+
+func TestBasicIntegerFormat(t *testing.T) {
+	cases := map[types.BasicKind]string{
+		types.Int:    "",
+		types.Int8:   "",
+		types.Int16:  "",
+		types.Int32:  "int32",
+		types.Int64:  "int64",
+		types.Uint8:  "",
+		types.Uint16: "",
+		types.Uint32: "int64",
+		types.Uint64: "int64",
+	}
+	for kind, want := range cases {
+		s, err := basic(types.Typ[kind])
+		if err != nil {
+			t.Fatalf("basic(%s): %v", types.Typ[kind], err)
+		}
+		if s.Type != "integer" || s.Format != want {
+			t.Errorf("basic(%s): want integer with format %q, got %s with format %q", types.Typ[kind], want, s.Type, s.Format)
+		}
+	}
+}
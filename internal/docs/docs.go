@@ -0,0 +1,267 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package docs generates API reference documentation from Go types and their
+// doc comments.
+package docs
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+)
+
+// Marker keys.
+const (
+	MarkerDefault = crd.MarkerDefault
+	MarkerEnum    = "kubebuilder:validation:Enum"
+)
+
+// A Page documents the kinds of an API group version, and the types they use.
+type Page struct {
+	Group   string
+	Version string
+
+	// Doc of the package that declares the kinds.
+	Doc string
+
+	// Kinds documented by the page.
+	Kinds []*Type
+
+	// Types documented by the page, including its kinds, in the order they
+	// were first referred to.
+	Types []*Type
+}
+
+// A Type documented by a Page.
+type Type struct {
+	Name   string
+	Anchor string
+	Doc    string
+
+	// Kind is true if the type is one of the kinds of the Page.
+	Kind bool
+
+	// Underlying type of a type that is not a struct, e.g. string.
+	Underlying *TypeRef
+
+	// Fields of a struct type. Fields of inline structs are included.
+	Fields []Field
+}
+
+// A Field of a Type.
+type Field struct {
+	// Name of the field, per its JSON tag.
+	Name     string
+	Type     TypeRef
+	Required bool
+	Default  string
+	Enum     []string
+	Doc      string
+}
+
+// Description of the field, including its allowed values if any.
+func (f Field) Description() string {
+	if len(f.Enum) == 0 {
+		return f.Doc
+	}
+	return strings.TrimSpace(f.Doc + " Allowed values: " + strings.Join(f.Enum, ", ") + ".")
+}
+
+// A TypeRef refers to the type of a field, e.g. map[string][]Foo.
+type TypeRef struct {
+	// Prefix of the type, e.g. map[string][].
+	Prefix string
+
+	// Name of the type, e.g. Foo.
+	Name string
+
+	// Anchor of the type, if it is documented by the Page.
+	Anchor string
+}
+
+// String returns the type as written in Go.
+func (r TypeRef) String() string {
+	return r.Prefix + r.Name
+}
+
+// A Builder builds Pages.
+type Builder struct {
+	schemas *crd.Schemas
+	page    *Page
+	types   map[*types.TypeName]*Type
+	anchors map[string]bool
+	queue   []*types.Named
+}
+
+// NewBuilder returns a Builder that documents kinds of the supplied package,
+// which must declare its API group. See crd.GroupVersion.
+func NewBuilder(p *packages.Package) (*Builder, error) {
+	group, version, err := crd.GroupVersion(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		schemas: crd.NewSchemas(p),
+		page: &Page{
+			Group:   group,
+			Version: version,
+			Doc:     crd.Description(comments.PackageDoc(p)),
+		},
+		types:   map[*types.TypeName]*Type{},
+		anchors: map[string]bool{},
+	}, nil
+}
+
+// Build returns a Page that documents the supplied kinds, which must be named
+// types, and every type they refer to.
+func (b *Builder) Build(kinds ...types.Object) *Page {
+	for _, o := range kinds {
+		n, ok := o.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		t := b.enqueue(n)
+		t.Kind = true
+		b.page.Kinds = append(b.page.Kinds, t)
+	}
+	for len(b.queue) > 0 {
+		n := b.queue[0]
+		b.queue = b.queue[1:]
+		b.document(n)
+	}
+	return b.page
+}
+
+// enqueue returns the Type of the supplied named type, queueing it to be
+// documented if it has not been already.
+func (b *Builder) enqueue(n *types.Named) *Type {
+	if t, ok := b.types[n.Obj()]; ok {
+		return t
+	}
+	t := &Type{Name: n.Obj().Name(), Anchor: b.anchor(n.Obj())}
+	b.types[n.Obj()] = t
+	b.page.Types = append(b.page.Types, t)
+	b.queue = append(b.queue, n)
+	return t
+}
+
+func (b *Builder) document(n *types.Named) {
+	t := b.types[n.Obj()]
+	t.Doc = crd.Description(b.schemas.Comments(n.Obj()))
+	s, ok := n.Underlying().(*types.Struct)
+	if !ok {
+		u := b.ref(n.Underlying())
+		t.Underlying = &u
+		return
+	}
+	t.Fields = b.fields(s)
+}
+
+func (b *Builder) fields(s *types.Struct) []Field {
+	var fields []Field
+	for _, f := range b.schemas.Fields(s) {
+		if f.Inline {
+			if in, ok := deref(f.Var.Type()).Underlying().(*types.Struct); ok {
+				fields = append(fields, b.fields(in)...)
+			}
+			continue
+		}
+		fields = append(fields, Field{
+			Name:     f.Name,
+			Type:     b.ref(f.Var.Type()),
+			Required: f.Required,
+			Default:  defaultOf(f.Markers),
+			Enum:     comments.List(last(f.Markers[MarkerEnum])),
+			Doc:      f.Description,
+		})
+	}
+	return fields
+}
+
+// ref returns a reference to the supplied type, queueing any named type it
+// refers to that should be documented.
+func (b *Builder) ref(t types.Type) TypeRef {
+	switch u := t.(type) {
+	case *types.Named:
+		r := TypeRef{Name: u.Obj().Name()}
+		if u.Obj().Pkg() == nil || b.schemas.Known(u) || strings.HasPrefix(u.Obj().Pkg().Path(), "k8s.io/") {
+			if u.Obj().Pkg() != nil {
+				r.Name = u.Obj().Pkg().Name() + "." + r.Name
+			}
+			return r
+		}
+		r.Anchor = b.enqueue(u).Anchor
+		return r
+	case *types.Pointer:
+		r := b.ref(u.Elem())
+		r.Prefix = "*" + r.Prefix
+		return r
+	case *types.Slice:
+		r := b.ref(u.Elem())
+		r.Prefix = "[]" + r.Prefix
+		return r
+	case *types.Array:
+		r := b.ref(u.Elem())
+		r.Prefix = fmt.Sprintf("[%d]", u.Len()) + r.Prefix
+		return r
+	case *types.Map:
+		r := b.ref(u.Elem())
+		r.Prefix = "map[" + b.ref(u.Key()).String() + "]" + r.Prefix
+		return r
+	}
+	return TypeRef{Name: types.TypeString(t, func(p *types.Package) string { return p.Name() })}
+}
+
+// anchor returns a unique anchor for the supplied type. Anchors are the lower
+// case name of the type, qualified by its package's name if that name is
+// taken.
+func (b *Builder) anchor(o *types.TypeName) string {
+	a := strings.ToLower(o.Name())
+	if b.anchors[a] && o.Pkg() != nil {
+		a = strings.ToLower(o.Pkg().Name() + "-" + o.Name())
+	}
+	b.anchors[a] = true
+	return a
+}
+
+// defaultOf returns the default value declared by the supplied markers. See
+// comments.MarkerDefault.
+func defaultOf(m comments.Markers) string {
+	if d := m[MarkerDefault]; len(d) > 0 {
+		return comments.Unquote(last(d))
+	}
+	return comments.Unquote(last(m[comments.MarkerDefault]))
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+func last(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[len(s)-1]
+}
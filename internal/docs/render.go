@@ -0,0 +1,134 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docs
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+// Formats in which a Page may be rendered.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Extensions of the files of each format.
+var Extensions = map[string]string{
+	FormatMarkdown: ".md",
+	FormatHTML:     ".html",
+}
+
+var funcs = map[string]interface{}{
+	"cell": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	},
+}
+
+var markdown = template.Must(template.New("markdown").Funcs(funcs).Parse(`
+# {{ .Page.Group }}/{{ .Page.Version }}
+{{ with .Page.Doc }}
+{{ . }}
+{{ end }}
+## Resource Types
+{{ range .Page.Kinds }}
+- [{{ .Name }}](#{{ .Anchor }})
+{{- end }}
+{{ range .Page.Types }}
+<a id="{{ .Anchor }}"></a>
+## {{ .Name }}
+{{ with .Doc }}
+{{ . }}
+{{ end }}
+{{- with .Underlying }}
+Underlying type: {{ template "ref" . }}
+{{ end }}
+{{- if .Fields }}
+| Field | Type | Required | Default | Description |
+|-------|------|----------|---------|-------------|
+{{- range .Fields }}
+| ` + "`{{ .Name }}`" + ` | {{ template "ref" .Type }} | {{ if .Required }}Yes{{ else }}No{{ end }} | {{ with .Default }}` + "`{{ cell . }}`" + `{{ end }} | {{ cell .Description }} |
+{{- end }}
+{{ end }}
+{{- end }}
+{{- define "ref" }}{{ if .Anchor }}{{ .Prefix }}[{{ .Name }}](#{{ .Anchor }}){{ else }}{{ .Prefix }}{{ .Name }}{{ end }}{{ end -}}
+`))
+
+var html = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Page.Group }}/{{ .Page.Version }}</title>
+</head>
+<body>
+<h1>{{ .Page.Group }}/{{ .Page.Version }}</h1>
+{{ with .Page.Doc }}<p>{{ . }}</p>{{ end }}
+<h2>Resource Types</h2>
+<ul>
+{{- range .Page.Kinds }}
+<li><a href="#{{ .Anchor }}">{{ .Name }}</a></li>
+{{- end }}
+</ul>
+{{ range .Page.Types }}
+<h2 id="{{ .Anchor }}">{{ .Name }}</h2>
+{{ with .Doc }}<p>{{ . }}</p>{{ end }}
+{{- with .Underlying }}
+<p>Underlying type: {{ template "ref" . }}</p>
+{{- end }}
+{{- if .Fields }}
+<table>
+<thead><tr><th>Field</th><th>Type</th><th>Required</th><th>Default</th><th>Description</th></tr></thead>
+<tbody>
+{{- range .Fields }}
+<tr><td><code>{{ .Name }}</code></td><td>{{ template "ref" .Type }}</td><td>{{ if .Required }}Yes{{ else }}No{{ end }}</td><td>{{ with .Default }}<code>{{ . }}</code>{{ end }}</td><td>{{ .Description }}</td></tr>
+{{- end }}
+</tbody>
+</table>
+{{- end }}
+{{ end }}
+</body>
+</html>
+{{- define "ref" }}{{ if .Anchor }}{{ .Prefix }}<a href="#{{ .Anchor }}">{{ .Name }}</a>{{ else }}{{ .Prefix }}{{ .Name }}{{ end }}{{ end -}}
+`))
+
+// Render the supplied Page in the supplied format. Rendered pages carry
+// generate.HeaderGenerated.
+func Render(p *Page, format string) ([]byte, error) {
+	data := struct {
+		Page *Page
+	}{Page: p}
+
+	// The header is written outside of the templates because html/template
+	// strips comments.
+	b := bytes.NewBufferString("<!-- " + generate.HeaderGenerated + " -->\n")
+	var err error
+	switch format {
+	case FormatMarkdown:
+		err = markdown.Execute(b, data)
+	case FormatHTML:
+		err = html.Execute(b, data)
+	default:
+		return nil, errors.Errorf("unknown format %q", format)
+	}
+	return b.Bytes(), errors.Wrapf(err, "cannot render %s", format)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docs
+
+import (
+	"testing"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+func TestRenderIsGenerated(t *testing.T) {
+	thing := &Type{
+		Name:   "Thing",
+		Anchor: "thing",
+		Doc:    "A Thing.",
+		Kind:   true,
+		Fields: []Field{{Name: "name", Type: TypeRef{Name: "string"}, Required: true, Doc: "Name of the <thing>."}},
+	}
+	p := &Page{Group: "example.org", Version: "v1", Kinds: []*Type{thing}, Types: []*Type{thing}}
+
+	for format := range Extensions {
+		t.Run(format, func(t *testing.T) {
+			b, err := Render(p, format)
+			if err != nil {
+				t.Fatalf("Render(...): %v", err)
+			}
+			if !generate.IsGenerated(b) {
+				t.Errorf("Render(...): want %q in\n%s", generate.HeaderGenerated, b)
+			}
+		})
+	}
+}
@@ -209,3 +209,134 @@ func IsNetworkNodeUsage() Matcher {
 func IsItems() Matcher {
 	return IsNamed(NameItems)
 }
+
+// A Requirement describes a struct field that an Object must have. Unlike a
+// Matcher a Requirement can explain why a field does not satisfy it.
+type Requirement struct {
+	// Name of the field.
+	Name string
+
+	// TypeSuffix the type of the field must end with, if any.
+	TypeSuffix string
+
+	// Embedded requires the field to be embedded.
+	Embedded bool
+
+	// Slice requires the field to be a slice.
+	Slice bool
+
+	// Fields that the (struct) type of the field must have.
+	Fields []Requirement
+}
+
+// Matcher returns a Matcher that returns true if the supplied field satisfies
+// the Requirement.
+func (r Requirement) Matcher() Matcher {
+	m := IsNamed(r.Name)
+	if r.TypeSuffix != "" {
+		m = IsTypeNamed(r.TypeSuffix, r.Name)
+	}
+	if r.Embedded {
+		m = m.And(IsEmbedded())
+	}
+	if r.Slice {
+		m = m.And(IsSlice())
+	}
+	if len(r.Fields) > 0 {
+		m = m.And(HasFieldThat(Matchers(r.Fields...)...))
+	}
+	return m
+}
+
+// String describes the Requirement, e.g. "embedded field ResourceSpec of type
+// github.com/netw-device-driver/ndd-runtime/apis/common/v1.ResourceSpec".
+func (r Requirement) String() string {
+	s := "field " + r.Name
+	if r.Embedded {
+		s = "embedded " + s
+	}
+	if r.Slice {
+		s = "slice " + s
+	}
+	if r.TypeSuffix != "" && r.TypeSuffix != r.Name {
+		s += " of type " + r.TypeSuffix
+	}
+	return s
+}
+
+// Matchers returns a Matcher for each of the supplied Requirements.
+func Matchers(rs ...Requirement) []Matcher {
+	m := make([]Matcher, 0, len(rs))
+	for _, r := range rs {
+		m = append(m, r.Matcher())
+	}
+	return m
+}
+
+// A Diagnosis explains whether a Requirement was satisfied.
+type Diagnosis struct {
+	// Description of what was required.
+	Description string
+
+	// Satisfied is true if the requirement was met.
+	Satisfied bool
+
+	// Reason the requirement was not met, if it was not.
+	Reason string
+
+	// Fields diagnoses the nested requirements of a field, if any.
+	Fields []Diagnosis
+}
+
+// Explain diagnoses whether the supplied Object satisfies each of the supplied
+// Requirements. The returned Diagnosis is satisfied exactly when Has would
+// return true for the Matchers of the supplied Requirements.
+func Explain(o types.Object, rs ...Requirement) Diagnosis {
+	d := Diagnosis{Description: o.Name(), Satisfied: true}
+	s := findStruct(o)
+	if s == nil {
+		d.Satisfied = false
+		d.Reason = "not a struct, or a slice or map of struct"
+		return d
+	}
+	for _, r := range rs {
+		fd := explain(s, r)
+		if !fd.Satisfied {
+			d.Satisfied = false
+			d.Reason = "does not have all required fields"
+		}
+		d.Fields = append(d.Fields, fd)
+	}
+	return d
+}
+
+func explain(s *types.Struct, r Requirement) Diagnosis {
+	d := Diagnosis{Description: r.String()}
+
+	var f *types.Var
+	for i := 0; i < s.NumFields(); i++ {
+		if IsNamed(r.Name)(s.Field(i)) {
+			f = s.Field(i)
+			break
+		}
+	}
+
+	switch {
+	case f == nil:
+		d.Reason = "missing"
+	case r.TypeSuffix != "" && !strings.HasSuffix(f.Type().String(), r.TypeSuffix):
+		d.Reason = "has type " + f.Type().String()
+	case r.Embedded && !f.Embedded():
+		d.Reason = "is not embedded"
+	case r.Slice && !IsSlice()(f):
+		d.Reason = "is not a slice"
+	}
+	if d.Reason != "" || len(r.Fields) == 0 {
+		d.Satisfied = d.Reason == ""
+		return d
+	}
+
+	nd := Explain(f, r.Fields...)
+	d.Satisfied, d.Reason, d.Fields = nd.Satisfied, nd.Reason, nd.Fields
+	return d
+}
@@ -23,6 +23,8 @@ import (
 	"go/token"
 	"go/types"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/dave/jennifer/jen"
 	"github.com/pkg/errors"
@@ -41,8 +43,34 @@ const (
 
 type options struct {
 	Matches       match.Object
+	PackageName   string
 	ImportAliases map[string]string
 	Headers       []string
+	WriteFile     FileWriter
+	RemoveFile    FileRemover
+}
+
+// A FileWriter persists the rendered contents of a generated file.
+type FileWriter func(file string, data []byte) error
+
+// WriteFile is the default FileWriter. It writes the supplied data to the
+// supplied file, creating it and its directory or truncating it as necessary.
+// It refuses to overwrite an existing file that was not generated by ndd-gen.
+func WriteFile(file string, data []byte) error {
+	generated, err := IsGeneratedFile(file)
+	if err != nil {
+		return err
+	}
+	if !generated {
+		return errors.Errorf("refusing to overwrite %s: it was not generated by ndd-gen", file)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil { // nolint:gosec
+		return errors.Wrap(err, "cannot create directory")
+	}
+
+	// gosec would prefer this to be written as 0600, but we're comfortable with
+	// it being world readable.
+	return errors.Wrap(ioutil.WriteFile(file, data, 0644), "cannot write file") // nolint:gosec
 }
 
 // A WriteOption configures method generation behaviour.
@@ -65,6 +93,86 @@ func WithMatcher(m match.Object) WriteOption {
 	}
 }
 
+// A FileRemover removes a previously generated file that would no longer
+// contain any methods.
+type FileRemover func(file string) error
+
+// RemoveFile is the default FileRemover. It removes the supplied file if it
+// exists and was generated by ndd-gen. Files that were not generated by
+// ndd-gen are left untouched.
+func RemoveFile(file string) error {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil
+	}
+	generated, err := IsGeneratedFile(file)
+	if err != nil || !generated {
+		return err
+	}
+	return errors.Wrap(os.Remove(file), "cannot remove stale Go file")
+}
+
+// IsGeneratedFile returns true if the supplied file does not exist, or if it
+// exists and was generated by ndd-gen.
+func IsGeneratedFile(file string) (bool, error) {
+	data, err := ioutil.ReadFile(file) // nolint:gosec
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "cannot read file")
+	}
+	return IsGenerated(data), nil
+}
+
+// IsGenerated returns true if the supplied data is that of a file generated
+// by ndd-gen. Per the convention of https://golang.org/s/generatedcode, a Go
+// file is generated if a line comment above its package clause is exactly
+// HeaderGenerated. Other files, e.g. YAML and HTML, are generated if their
+// first line is a comment that is exactly HeaderGenerated. Files that merely
+// mention HeaderGenerated elsewhere are not generated.
+func IsGenerated(data []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", data, parser.PackageClauseOnly|parser.ParseComments)
+	if err == nil {
+		for _, g := range f.Comments {
+			if g.Pos() > f.Package {
+				break
+			}
+			for _, c := range g.List {
+				if c.Text == "// "+HeaderGenerated {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	switch string(bytes.TrimSpace(line)) {
+	case "# " + HeaderGenerated, "<!-- " + HeaderGenerated + " -->":
+		return true
+	}
+	return false
+}
+
+// WithFileWriter specifies the FileWriter used to persist generated files.
+// Files are written to disk using WriteFile by default.
+func WithFileWriter(fw FileWriter) WriteOption {
+	return func(o *options) {
+		o.WriteFile = fw
+	}
+}
+
+// WithFileRemover specifies the FileRemover used to remove previously
+// generated files that would no longer contain any methods. Files are removed
+// from disk using RemoveFile by default.
+func WithFileRemover(fr FileRemover) WriteOption {
+	return func(o *options) {
+		o.RemoveFile = fr
+	}
+}
+
 // WithImportAliases configures a map of import paths to aliases that will be
 // used when generating code. For example if a generated method requires
 // "example.org/foo/bar" it may refer to that package as "foobar" by supplying
@@ -75,18 +183,60 @@ func WithImportAliases(ia map[string]string) WriteOption {
 	}
 }
 
+// WithPackageName specifies the name of the package of the generated file,
+// for files that are written to a package other than the one whose code they
+// are generated from, e.g. a fake subpackage. Generated files belong to the
+// supplied package by default.
+func WithPackageName(name string) WriteOption {
+	return func(o *options) {
+		o.PackageName = name
+	}
+}
+
 // WriteMethods writes the supplied methods for each object in the supplied
 // package to the supplied file. Use WithMatcher to limit the objects for which
 // methods will be written. Methods will not be generated if a method with the
 // same name is already defined for the object outside of the supplied filename.
-// Files will not be written if they would contain no methods.
+// Files will not be written if they would contain no methods; a previously
+// generated file that would now contain no methods is removed instead. Use
+// WithFileWriter and WithFileRemover to change how generated files are
+// persisted.
 func WriteMethods(p *packages.Package, ms method.Set, file string, wo ...WriteOption) error {
 	opts := &options{Matches: func(o types.Object) bool { return true }}
 	for _, fn := range wo {
 		fn(opts)
 	}
+	return WriteCode(p, file, func(f *jen.File) {
+		for _, n := range p.Types.Scope().Names() {
+			o := p.Types.Scope().Lookup(n)
+			if !opts.Matches(o) {
+				continue
+			}
+			ms.Write(f, o, method.DefinedOutside(p.Fset, file))
+		}
+	}, wo...)
+}
+
+// WriteCode writes the code added to a file by the supplied function to the
+// supplied file, which belongs to the supplied package. Generated files carry
+// the same headers as those written by WriteMethods. Files will not be written
+// if they would contain no declarations; a previously generated file that
+// would now contain no declarations is removed instead. Use WithFileWriter and
+// WithFileRemover to change how generated files are persisted.
+func WriteCode(p *packages.Package, file string, code func(f *jen.File), wo ...WriteOption) error {
+	opts := &options{
+		WriteFile:  WriteFile,
+		RemoveFile: RemoveFile,
+	}
+	for _, fn := range wo {
+		fn(opts)
+	}
 
-	f := jen.NewFile(p.Name)
+	name := p.Name
+	if opts.PackageName != "" {
+		name = opts.PackageName
+	}
+	f := jen.NewFile(name)
 	for path, alias := range opts.ImportAliases {
 		f.ImportAlias(path, alias)
 	}
@@ -98,13 +248,7 @@ func WriteMethods(p *packages.Package, ms method.Set, file string, wo ...WriteOp
 	}
 	f.HeaderComment(HeaderGenerated)
 
-	for _, n := range p.Types.Scope().Names() {
-		o := p.Types.Scope().Lookup(n)
-		if !opts.Matches(o) {
-			continue
-		}
-		ms.Write(f, o, method.DefinedOutside(p.Fset, file))
-	}
+	code(f)
 
 	b := &bytes.Buffer{}
 	if err := f.Render(b); err != nil {
@@ -112,13 +256,22 @@ func WriteMethods(p *packages.Package, ms method.Set, file string, wo ...WriteOp
 	}
 
 	if ProducedNothing(b.Bytes()) {
-		return nil
+		return opts.RemoveFile(file)
 	}
 
-	// gosec would prefer this to be written as 0600, but we're comfortable with
-	// it being world readable.
-	return errors.Wrap(ioutil.WriteFile(file, b.Bytes(), 0644), "cannot write Go file") // nolint:gosec
+	return opts.WriteFile(file, b.Bytes())
+}
 
+// WriteData persists the supplied data, which was rendered by something other
+// than WriteMethods (e.g. a YAML manifest), to the supplied file. The data must
+// carry HeaderGenerated. Use WithFileWriter to change how the file is
+// persisted.
+func WriteData(file string, data []byte, wo ...WriteOption) error {
+	opts := &options{WriteFile: WriteFile}
+	for _, fn := range wo {
+		fn(opts)
+	}
+	return opts.WriteFile(file, data)
 }
 
 // ProducedNothing returns true if the supplied data is either not a valid Go
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"testing"
+)
+
+func TestIsGenerated(t *testing.T) {
+	cases := map[string]struct {
+		data string
+		want bool
+	}{
+		"GeneratedGo": {
+			data: `//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2021 Wim Henderickx.
+*/
+
+// Code generated by ndd-gen. DO NOT EDIT.
+
+package v1
+
+func (t *Thing) Get() {}
+`,
+			want: true,
+		},
+		"GoWithoutHeader": {
+			data: "package v1\n\ntype Thing struct{}\n",
+			want: false,
+		},
+		"GoQuotingHeaderInString": {
+			data: `package v1
+
+const header = "Code generated by ndd-gen. DO NOT EDIT."
+`,
+			want: false,
+		},
+		"GoQuotingHeaderInComment": {
+			data: `package v1
+
+// Code generated by ndd-gen. DO NOT EDIT.
+type Thing struct{}
+`,
+			want: false,
+		},
+		"GoHeaderInPackageDoc": {
+			data: `// Package v1 is mentioned in
+// Code generated by ndd-gen. DO NOT EDIT. docs.
+package v1
+`,
+			want: false,
+		},
+		"GoGeneratedByAnotherTool": {
+			data: "// Code generated by controller-gen. DO NOT EDIT.\n\npackage v1\n",
+			want: false,
+		},
+		"GeneratedYAML": {
+			data: "# Code generated by ndd-gen. DO NOT EDIT.\n---\nkind: ClusterRole\n",
+			want: true,
+		},
+		"YAMLQuotingHeader": {
+			data: "---\nkind: ClusterRole\n# Code generated by ndd-gen. DO NOT EDIT.\n",
+			want: false,
+		},
+		"GeneratedHTML": {
+			data: "<!-- Code generated by ndd-gen. DO NOT EDIT. -->\n# API Reference\n",
+			want: true,
+		},
+		"MarkdownQuotingHeader": {
+			data: "# Notes\n\nGenerated files carry `Code generated by ndd-gen. DO NOT EDIT.`\n",
+			want: false,
+		},
+		"Empty": {
+			data: "",
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := IsGenerated([]byte(tc.data)); got != tc.want {
+				t.Errorf("IsGenerated(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
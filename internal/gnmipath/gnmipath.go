@@ -0,0 +1,440 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gnmipath generates functions that build the gNMI paths of the YANG
+// schema nodes of managed resources.
+package gnmipath
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+)
+
+// Kinds of YANG schema node.
+const (
+	NodeContainer = "container"
+	NodeList      = "list"
+	NodeLeaf      = "leaf"
+	NodeLeafList  = "leaf-list"
+)
+
+// A Key of a YANG list.
+type Key struct {
+	// Name of the key leaf.
+	Name string
+
+	// Param is the name of the parameter of a Builder that supplies the value
+	// of the key.
+	Param string
+
+	// Type of the key leaf.
+	Type types.Type
+}
+
+// An Elem is an element of a gNMI path.
+type Elem struct {
+	Name string
+	Keys []Key
+}
+
+// A Builder is a function that builds the gNMI path of a YANG schema node of a
+// managed resource.
+type Builder struct {
+	// Name of the function.
+	Name string
+
+	// Resource is the name of the managed resource.
+	Resource string
+
+	// Node is the kind of schema node, e.g. NodeContainer.
+	Node string
+
+	// Elems of the path.
+	Elems []Elem
+}
+
+// Keys returns the keys of every element of the path, in order.
+func (b Builder) Keys() []Key {
+	var keys []Key
+	for _, e := range b.Elems {
+		keys = append(keys, e.Keys...)
+	}
+	return keys
+}
+
+// String returns the schema path of the Builder, e.g.
+// /interface[name=*]/description.
+func (b Builder) String() string {
+	s := &strings.Builder{}
+	for _, e := range b.Elems {
+		s.WriteString("/" + e.Name)
+		for _, k := range e.Keys {
+			s.WriteString("[" + k.Name + "=*]")
+		}
+	}
+	return s.String()
+}
+
+// Builders returns a Builder for every schema node of the spec of the supplied
+// managed resource, whose package's comments are supplied. Paths begin at the
+// fields of the spec that are marked with an absolute
+// comments.MarkerYangPath, and continue through nested structs, the items of
+// slices and maps, and fields of other types, which are leaves. Each field is
+// at the path element named after its JSON name, or at its relative
+// comments.MarkerYangPath. The fields of a struct that are marked with
+// comments.MarkerKey are the keys of its element. Builders are named after the
+// resource and the fields that lead to their node, e.g. InterfaceMtuPath.
+func Builders(c comments.Comments, o types.Object) ([]Builder, error) {
+	v, _, _ := types.LookupFieldOrMethod(o.Type(), true, o.Pkg(), fields.NameSpec)
+	spec, ok := v.(*types.Var)
+	if !ok || !spec.IsField() {
+		return nil, errors.Errorf("%s has no %s field", o.Name(), fields.NameSpec)
+	}
+
+	w := &walker{comments: c, object: o, visiting: map[*types.TypeName]bool{}, names: map[string]bool{}}
+	w.node(spec.Type(), node{name: o.Name()}, true, false)
+	if len(w.errs) > 0 {
+		return nil, errors.New(strings.Join(w.errs, "; "))
+	}
+	return w.builders, nil
+}
+
+// Write the supplied Builders, whose resources are declared by the supplied
+// package, to the supplied file. Paths are of the type of the supplied gNMI
+// package, e.g. github.com/openconfig/gnmi/proto/gnmi.
+func Write(f *jen.File, pkg *types.Package, gnmi string, bs []Builder) {
+	for _, b := range bs {
+		keys := b.Keys()
+		params := make([]jen.Code, 0, len(keys))
+		for _, k := range keys {
+			params = append(params, jen.Id(k.Param).Add(typeCode(pkg, k.Type)))
+		}
+		elems := make([]jen.Code, 0, len(b.Elems))
+		for _, e := range b.Elems {
+			d := jen.Dict{jen.Id("Name"): jen.Lit(e.Name)}
+			if len(e.Keys) > 0 {
+				kd := jen.Dict{}
+				for _, k := range e.Keys {
+					kd[jen.Lit(k.Name)] = value(k)
+				}
+				d[jen.Id("Key")] = jen.Map(jen.String()).String().Values(kd)
+			}
+			elems = append(elems, jen.Values(d))
+		}
+
+		f.Commentf("%s returns the gNMI path of the %s %s of a %s.", b.Name, b.Node, b, b.Resource)
+		f.Func().Id(b.Name).Params(params...).Op("*").Qual(gnmi, "Path").Block(
+			jen.Return(jen.Op("&").Qual(gnmi, "Path").Values(jen.Dict{
+				jen.Id("Elem"): jen.Index().Op("*").Qual(gnmi, "PathElem").Custom(jen.Options{Open: "{", Close: "}", Separator: ",", Multi: true}, elems...),
+			})),
+		)
+	}
+}
+
+// A node of the schema of a managed resource.
+type node struct {
+	// name of the Builder of the node.
+	name string
+
+	// elems of the path of the node.
+	elems []Elem
+
+	// rooted is true if the node has an absolute path.
+	rooted bool
+}
+
+// A walker walks the fields of the spec of a managed resource, adding a
+// Builder for each node with an absolute path.
+type walker struct {
+	comments comments.Comments
+	object   types.Object
+	visiting map[*types.TypeName]bool
+	names    map[string]bool
+
+	builders []Builder
+	errs     []string
+}
+
+// node walks the supplied node, which is of the supplied type. The fields of
+// inline structs belong to their parent node. Items of lists are nodes of
+// their list.
+func (w *walker) node(t types.Type, n node, inline, item bool) {
+	t = elem(t)
+	if nt, ok := t.(*types.Named); ok {
+		if _, ok := nt.Underlying().(*types.Struct); ok && nt.Obj().Pkg() != w.object.Pkg() {
+			// Only the comments of the resource's package are known.
+			return
+		}
+		if w.visiting[nt.Obj()] {
+			// Recursive types have no finite paths.
+			return
+		}
+		w.visiting[nt.Obj()] = true
+		defer delete(w.visiting, nt.Obj())
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		if !inline {
+			n = w.keyed(u, n)
+			kind := NodeContainer
+			if item || len(n.elems) > 0 && len(n.elems[len(n.elems)-1].Keys) > 0 {
+				kind = NodeList
+			}
+			w.add(n, kind)
+		}
+		w.fields(u, n)
+	case *types.Slice:
+		if b, ok := u.Elem().(*types.Basic); ok && b.Kind() == types.Byte {
+			w.add(n, NodeLeaf)
+			return
+		}
+		w.items(u.Elem(), n, item)
+	case *types.Array:
+		w.items(u.Elem(), n, item)
+	case *types.Map:
+		w.items(u.Elem(), n, item)
+	default:
+		kind := NodeLeaf
+		if item {
+			kind = NodeLeafList
+		}
+		w.add(n, kind)
+	}
+}
+
+// items walks the items of a list node, which are of the supplied type.
+func (w *walker) items(t types.Type, n node, item bool) {
+	if item {
+		// Lists of lists are not modelled by YANG.
+		return
+	}
+	w.node(t, n, false, true)
+}
+
+// fields walks the fields of the supplied struct, which is at the supplied
+// node.
+func (w *walker) fields(s *types.Struct, n node) {
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		name, inline := jsonName(f, s.Tag(i))
+		if name == "-" || !f.Exported() {
+			continue
+		}
+		m := comments.ParseMarkers(w.comments.Field(f))
+
+		c := node{name: n.name + f.Name(), elems: n.elems, rooted: n.rooted}
+		switch yp := m.YangPath(); {
+		case strings.HasPrefix(yp, "/"):
+			elems, err := split(yp[1:])
+			if err != nil {
+				w.errorf("field %s: %s", f.Name(), err)
+				continue
+			}
+			c = node{name: w.object.Name(), elems: elems, rooted: true}
+			if n.rooted {
+				c.name = n.name + f.Name()
+			}
+		case yp != "":
+			elems, err := split(yp)
+			if err != nil {
+				w.errorf("field %s: %s", f.Name(), err)
+				continue
+			}
+			c.elems = append(append([]Elem{}, n.elems...), elems...)
+		case inline:
+			w.node(f.Type(), n, true, false)
+			continue
+		default:
+			c.elems = append(append([]Elem{}, n.elems...), Elem{Name: name})
+		}
+		w.node(f.Type(), c, false, false)
+	}
+}
+
+// keyed returns the supplied node with the keys of its struct added to its
+// last path element.
+func (w *walker) keyed(s *types.Struct, n node) node {
+	if len(n.elems) == 0 {
+		return n
+	}
+	last := n.elems[len(n.elems)-1]
+	keys := append([]Key{}, last.Keys...)
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		name, _ := jsonName(f, s.Tag(i))
+		m := comments.ParseMarkers(w.comments.Field(f))
+		if name == "-" || !f.Exported() || !m.IsKey() {
+			continue
+		}
+		if yp := m.YangPath(); yp != "" {
+			name = yp[strings.LastIndex(yp, "/")+1:]
+		}
+		t := elem(f.Type())
+		if _, ok := t.Underlying().(*types.Basic); !ok {
+			w.errorf("key %s of %s is not of a basic type", f.Name(), last.Name)
+			continue
+		}
+		for _, k := range keys {
+			if k.Name == name {
+				w.errorf("duplicate key %s of %s", name, last.Name)
+			}
+		}
+		keys = append(keys, Key{Name: name, Type: t})
+	}
+
+	elems := append([]Elem{}, n.elems...)
+	elems[len(elems)-1] = Elem{Name: last.Name, Keys: keys}
+	n.elems = elems
+	return n
+}
+
+// add a Builder of the supplied kind for the supplied node, if it has an
+// absolute path.
+func (w *walker) add(n node, kind string) {
+	if !n.rooted {
+		return
+	}
+	name := n.name + "Path"
+	if w.names[name] {
+		w.errorf("duplicate path builder %s", name)
+		return
+	}
+	w.names[name] = true
+
+	params := map[string]bool{}
+	elems := make([]Elem, 0, len(n.elems))
+	for _, e := range n.elems {
+		keys := make([]Key, 0, len(e.Keys))
+		for _, k := range e.Keys {
+			k.Param = param(params, e.Name, k.Name)
+			keys = append(keys, k)
+		}
+		elems = append(elems, Elem{Name: e.Name, Keys: keys})
+	}
+	w.builders = append(w.builders, Builder{Name: name, Resource: w.object.Name(), Node: kind, Elems: elems})
+}
+
+func (w *walker) errorf(format string, args ...interface{}) {
+	w.errs = append(w.errs, w.object.Name()+": "+fmt.Sprintf(format, args...))
+}
+
+// param returns a parameter name for the supplied key of the supplied
+// element that is not one of the supplied names, and adds it to them.
+func param(used map[string]bool, elem, key string) string {
+	p := ident(key)
+	if used[p] || token.IsKeyword(p) {
+		p = ident(elem + "-" + key)
+	}
+	for i, base := 1, p; used[p] || token.IsKeyword(p); i++ {
+		p = fmt.Sprintf("%s%d", base, i)
+	}
+	used[p] = true
+	return p
+}
+
+// ident returns the supplied YANG identifier, e.g. admin-state, as an
+// unexported Go identifier, e.g. adminState.
+func ident(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	b := &strings.Builder{}
+	for i, w := range words {
+		r := []rune(w)
+		if i == 0 {
+			r[0] = unicode.ToLower(r[0])
+		} else {
+			r[0] = unicode.ToUpper(r[0])
+		}
+		b.WriteString(string(r))
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "k" + b.String()
+	}
+	return b.String()
+}
+
+// split returns the elements of the supplied relative schema path.
+func split(path string) ([]Elem, error) {
+	var elems []Elem
+	for _, s := range strings.Split(path, "/") {
+		if s == "" {
+			return nil, errors.Errorf("+%s=%s is not a valid schema path", comments.MarkerYangPath, path)
+		}
+		elems = append(elems, Elem{Name: s})
+	}
+	return elems, nil
+}
+
+// jsonName returns the JSON name of the supplied field, and whether it is
+// inline. Fields without a JSON name are named after the field.
+func jsonName(f *types.Var, tag string) (string, bool) {
+	parts := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+	for _, o := range parts[1:] {
+		if o == "inline" {
+			return parts[0], true
+		}
+	}
+	if parts[0] == "" {
+		return f.Name(), f.Embedded()
+	}
+	return parts[0], false
+}
+
+// elem returns the supplied type, or the type it points to if it is a
+// pointer.
+func elem(t types.Type) types.Type {
+	for {
+		p, ok := t.(*types.Pointer)
+		if !ok {
+			return t
+		}
+		t = p.Elem()
+	}
+}
+
+// typeCode returns the supplied type, which is named or basic, as it is
+// referred to by the supplied package.
+func typeCode(pkg *types.Package, t types.Type) jen.Code {
+	if n, ok := t.(*types.Named); ok {
+		if n.Obj().Pkg() == nil || n.Obj().Pkg() == pkg {
+			return jen.Id(n.Obj().Name())
+		}
+		return jen.Qual(n.Obj().Pkg().Path(), n.Obj().Name())
+	}
+	return jen.Id(t.String())
+}
+
+// value returns the value of the parameter of the supplied key as a string.
+func value(k Key) jen.Code {
+	b, _ := k.Type.Underlying().(*types.Basic)
+	if b == nil || b.Info()&types.IsString == 0 {
+		return jen.Qual("fmt", "Sprint").Call(jen.Id(k.Param))
+	}
+	if _, named := k.Type.(*types.Named); named {
+		return jen.String().Call(jen.Id(k.Param))
+	}
+	return jen.Id(k.Param)
+}
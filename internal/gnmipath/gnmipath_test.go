@@ -0,0 +1,268 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gnmipath
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+)
+
+// interfaces declares a managed resource whose spec models nested YANG lists,
+// whose keys have colliding names, and leaves at relative and absolute paths.
+const interfaces = `package v1
+
+type Kind string
+
+type Address struct {
+	// +ndd:key
+	IP string ` + "`json:\"ip\"`" + `
+	// +ndd:key
+	// +ndd:yang:path=type
+	Kind Kind ` + "`json:\"kind\"`" + `
+}
+
+type Subinterface struct {
+	// +ndd:key
+	Name string ` + "`json:\"name\"`" + `
+	Addresses []Address ` + "`json:\"address\"`" + `
+}
+
+type Interface struct {
+	// +ndd:key
+	Name string ` + "`json:\"name\"`" + `
+	// +ndd:yang:path=config/description
+	Description *string ` + "`json:\"description,omitempty\"`" + `
+	Subinterfaces []Subinterface ` + "`json:\"subinterface\"`" + `
+	// +ndd:yang:path=/network-instance/name
+	NetworkInstance string ` + "`json:\"networkInstance\"`" + `
+	Tags []string ` + "`json:\"tag\"`" + `
+}
+
+type ThingSpec struct {
+	Active bool ` + "`json:\"active\"`" + `
+	// +ndd:yang:path=/interface
+	Interfaces []Interface ` + "`json:\"interfaces\"`" + `
+}
+
+type Thing struct {
+	Spec ThingSpec ` + "`json:\"spec\"`" + `
+}
+`
+
+const subinterfacePath = `package v1
+
+import gnmi "example.org/gnmi"
+
+// ThingSubinterfacesAddressesPath returns the gNMI path of the list /interface[name=*]/subinterface[name=*]/address[ip=*][type=*] of a Thing.
+func ThingSubinterfacesAddressesPath(name string, subinterfaceName string, ip string, addressType Kind) *gnmi.Path {
+	return &gnmi.Path{Elem: []*gnmi.PathElem{
+		{
+			Key:  map[string]string{"name": name},
+			Name: "interface",
+		},
+		{
+			Key:  map[string]string{"name": subinterfaceName},
+			Name: "subinterface",
+		},
+		{
+			Key: map[string]string{
+				"ip":   ip,
+				"type": string(addressType),
+			},
+			Name: "address",
+		},
+	}}
+}
+`
+
+func load(t *testing.T, src string) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "types.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := (&types.Config{}).Check("example.org/v1", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packages.Package{PkgPath: p.Path(), Name: p.Name(), Fset: fset, Syntax: []*ast.File{f}, Types: p}
+}
+
+// describe returns the supplied Builder as its name, kind of node, schema path
+// and parameters, e.g. ThingPath list /interface[name=*](name).
+func describe(b Builder) string {
+	params := make([]string, 0, len(b.Keys()))
+	for _, k := range b.Keys() {
+		params = append(params, k.Param)
+	}
+	return fmt.Sprintf("%s %s %s(%s)", b.Name, b.Node, b, strings.Join(params, ", "))
+}
+
+func TestBuilders(t *testing.T) {
+	p := load(t, interfaces)
+	bs, err := Builders(comments.In(p), p.Types.Scope().Lookup("Thing"))
+	if err != nil {
+		t.Fatalf("Builders(...): %v", err)
+	}
+
+	want := []string{
+		"ThingPath list /interface[name=*](name)",
+		"ThingNamePath leaf /interface[name=*]/name(name)",
+		"ThingDescriptionPath leaf /interface[name=*]/config/description(name)",
+		"ThingSubinterfacesPath list /interface[name=*]/subinterface[name=*](name, subinterfaceName)",
+		"ThingSubinterfacesNamePath leaf /interface[name=*]/subinterface[name=*]/name(name, subinterfaceName)",
+		"ThingSubinterfacesAddressesPath list /interface[name=*]/subinterface[name=*]/address[ip=*][type=*](name, subinterfaceName, ip, addressType)",
+		"ThingSubinterfacesAddressesIPPath leaf /interface[name=*]/subinterface[name=*]/address[ip=*][type=*]/ip(name, subinterfaceName, ip, addressType)",
+		"ThingSubinterfacesAddressesKindPath leaf /interface[name=*]/subinterface[name=*]/address[ip=*][type=*]/type(name, subinterfaceName, ip, addressType)",
+		"ThingNetworkInstancePath leaf /network-instance/name()",
+		"ThingTagsPath leaf-list /interface[name=*]/tag(name)",
+	}
+	got := make([]string, 0, len(bs))
+	for _, b := range bs {
+		got = append(got, describe(b))
+	}
+	if strings.Join(want, "\n") != strings.Join(got, "\n") {
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{A: want, B: got, FromFile: "want", ToFile: "got", Context: 3})
+		t.Errorf("Builders(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestBuildersErrors(t *testing.T) {
+	cases := map[string]struct {
+		src  string
+		want string
+	}{
+		"DuplicateBuilder": {
+			src: `package v1
+
+type ThingSpec struct {
+	// +ndd:yang:path=/interface/name
+	Interface string
+	// +ndd:yang:path=/system/name
+	System string
+}
+
+type Thing struct {
+	Spec ThingSpec
+}
+`,
+			want: "Thing: duplicate path builder ThingPath",
+		},
+		"DuplicateKey": {
+			src: `package v1
+
+type Interface struct {
+	// +ndd:key
+	Name string ` + "`json:\"name\"`" + `
+	// +ndd:key
+	// +ndd:yang:path=name
+	Other string ` + "`json:\"other\"`" + `
+}
+
+type ThingSpec struct {
+	// +ndd:yang:path=/interface
+	Interfaces []Interface
+}
+
+type Thing struct {
+	Spec ThingSpec
+}
+`,
+			want: "Thing: duplicate key name of interface",
+		},
+		"InvalidPath": {
+			src: `package v1
+
+type ThingSpec struct {
+	// +ndd:yang:path=/interface//name
+	Name string
+}
+
+type Thing struct {
+	Spec ThingSpec
+}
+`,
+			want: "Thing: field Name: +ndd:yang:path=interface//name is not a valid schema path",
+		},
+		"NoSpec": {
+			src:  "package v1\n\ntype Thing struct{}\n",
+			want: "Thing has no Spec field",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := load(t, tc.src)
+			_, err := Builders(comments.In(p), p.Types.Scope().Lookup("Thing"))
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("Builders(...): want error %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParam(t *testing.T) {
+	used := map[string]bool{}
+	want := []string{"name", "subinterfaceName", "subinterfaceName1", "addressType", "k0", "adminState"}
+	got := []string{
+		param(used, "interface", "name"),
+		param(used, "subinterface", "name"),
+		param(used, "subinterface", "name"),
+		param(used, "address", "type"),
+		param(used, "vlan", "0"),
+		param(used, "interface", "admin-state"),
+	}
+	if strings.Join(want, ",") != strings.Join(got, ",") {
+		t.Errorf("param(...): want %v, got %v", want, got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	p := load(t, interfaces)
+	bs, err := Builders(comments.In(p), p.Types.Scope().Lookup("Thing"))
+	if err != nil {
+		t.Fatalf("Builders(...): %v", err)
+	}
+	var addresses []Builder
+	for _, b := range bs {
+		if b.Name == "ThingSubinterfacesAddressesPath" {
+			addresses = append(addresses, b)
+		}
+	}
+
+	f := jen.NewFile(p.Name)
+	f.ImportAlias("example.org/gnmi", "gnmi")
+	Write(f, p.Types, "example.org/gnmi", addresses)
+
+	got := fmt.Sprintf("%#v", f)
+	if got != subinterfacePath {
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{A: difflib.SplitLines(subinterfacePath), B: difflib.SplitLines(got), FromFile: "want", ToFile: "got", Context: 3})
+		t.Errorf("Write(...): -want, +got:\n%s", diff)
+	}
+}
@@ -12,84 +12,122 @@ import (
 // matches.
 type Object func(o types.Object) bool
 
-// Managed returns an Object matcher that returns true if the supplied Object is
-// a ndd managed resource.
-func Managed() Object {
+// A Shape is the set of fields that identifies a kind of ndd resource.
+type Shape struct {
+	// Name of the kind of resource, e.g. "managed".
+	Name string
+
+	// Fields the resource must have.
+	Fields []fields.Requirement
+}
+
+// Matches returns an Object matcher that returns true if the supplied Object
+// has all fields of the Shape.
+func (s Shape) Matches() Object {
 	return func(o types.Object) bool {
-		return fields.Has(o,
-			fields.IsTypeMeta().And(fields.IsEmbedded()),
-			fields.IsObjectMeta().And(fields.IsEmbedded()),
-			fields.IsSpec().And(fields.HasFieldThat(
-				fields.IsResourceSpec().And(fields.IsEmbedded()),
-			)),
-			fields.IsStatus().And(fields.HasFieldThat(
-				fields.IsResourceStatus().And(fields.IsEmbedded()),
-			)),
-		)
+		return fields.Has(o, fields.Matchers(s.Fields...)...)
 	}
 }
 
-// ManagedList returns an Object matcher that returns true if the supplied
-// Object is a list of ndd managed resource.
-func ManagedList() Object {
-	return func(o types.Object) bool {
-		return fields.Has(o,
-			fields.IsTypeMeta().And(fields.IsEmbedded()),
-			fields.IsItems().And(fields.IsSlice()).And(fields.HasFieldThat(
-				fields.IsTypeMeta().And(fields.IsEmbedded()),
-				fields.IsObjectMeta().And(fields.IsEmbedded()),
-				fields.IsSpec().And(fields.HasFieldThat(
-					fields.IsResourceSpec().And(fields.IsEmbedded()),
-				)),
-				fields.IsStatus().And(fields.HasFieldThat(
-					fields.IsResourceStatus().And(fields.IsEmbedded()),
-				)),
-			)),
-		)
+// Explain diagnoses which fields of the Shape the supplied Object has.
+func (s Shape) Explain(o types.Object) fields.Diagnosis {
+	return fields.Explain(o, s.Fields...)
+}
+
+var (
+	typeMeta   = fields.Requirement{Name: fields.NameTypeMeta, TypeSuffix: fields.TypeSuffixTypeMeta, Embedded: true}
+	objectMeta = fields.Requirement{Name: fields.NameObjectMeta, TypeSuffix: fields.TypeSuffixObjectMeta, Embedded: true}
+)
+
+// ManagedShape returns the Shape of a ndd managed resource.
+func ManagedShape() Shape {
+	return Shape{Name: "managed", Fields: managedFields()}
+}
+
+// ManagedListShape returns the Shape of a list of ndd managed resources.
+func ManagedListShape() Shape {
+	return Shape{Name: "managed-list", Fields: []fields.Requirement{
+		typeMeta,
+		{Name: fields.NameItems, Slice: true, Fields: managedFields()},
+	}}
+}
+
+// NetworkNodeShape returns the Shape of a NetworkNode.
+func NetworkNodeShape() Shape {
+	return Shape{Name: "network-node", Fields: []fields.Requirement{
+		typeMeta,
+		objectMeta,
+		{Name: fields.NameSpec, TypeSuffix: fields.TypeSuffixSpec},
+		{Name: fields.NameStatus, TypeSuffix: fields.TypeSuffixStatus, Fields: []fields.Requirement{
+			{Name: fields.NameNetworkNodeStatus, TypeSuffix: fields.TypeSuffixNetworkNodeStatus, Embedded: true},
+		}},
+	}}
+}
+
+// NetworkNodeUsageShape returns the Shape of a NetworkNodeUsage.
+func NetworkNodeUsageShape() Shape {
+	return Shape{Name: "network-node-usage", Fields: networkNodeUsageFields()}
+}
+
+// NetworkNodeUsageListShape returns the Shape of a list of NetworkNode usages.
+func NetworkNodeUsageListShape() Shape {
+	return Shape{Name: "network-node-usage-list", Fields: []fields.Requirement{
+		typeMeta,
+		{Name: fields.NameItems, Slice: true, Fields: networkNodeUsageFields()},
+	}}
+}
+
+// Shapes returns the Shapes of all common ndd resources.
+func Shapes() []Shape {
+	return []Shape{
+		ManagedShape(),
+		ManagedListShape(),
+		NetworkNodeShape(),
+		NetworkNodeUsageShape(),
+		NetworkNodeUsageListShape(),
 	}
 }
 
-// NetworkNode returns an Object matcher that returns true if the supplied
-// Object is a NetworkNode.
-func NetworkNode() Object {
-	return func(o types.Object) bool {
-		return fields.Has(o,
-			fields.IsTypeMeta().And(fields.IsEmbedded()),
-			fields.IsObjectMeta().And(fields.IsEmbedded()),
-			fields.IsSpec(),
-			fields.IsStatus().And(fields.HasFieldThat(
-				fields.IsNetworkNodeStatus().And(fields.IsEmbedded()),
-			)),
-		)
+func managedFields() []fields.Requirement {
+	return []fields.Requirement{
+		typeMeta,
+		objectMeta,
+		{Name: fields.NameSpec, TypeSuffix: fields.TypeSuffixSpec, Fields: []fields.Requirement{
+			{Name: fields.NameResourceSpec, TypeSuffix: fields.TypeSuffixResourceSpec, Embedded: true},
+		}},
+		{Name: fields.NameStatus, TypeSuffix: fields.TypeSuffixStatus, Fields: []fields.Requirement{
+			{Name: fields.NameResourceStatus, TypeSuffix: fields.TypeSuffixResourceStatus, Embedded: true},
+		}},
 	}
 }
 
-// NetworkNodeUsage returns an Object matcher that returns true if the supplied
-// Object is a NetworkNodeUsage.
-func NetworkNodeUsage() Object {
-	return func(o types.Object) bool {
-		return fields.Has(o,
-			fields.IsTypeMeta().And(fields.IsEmbedded()),
-			fields.IsObjectMeta().And(fields.IsEmbedded()),
-			fields.IsNetworkNodeUsage().And(fields.IsEmbedded()),
-		)
+func networkNodeUsageFields() []fields.Requirement {
+	return []fields.Requirement{
+		typeMeta,
+		objectMeta,
+		{Name: fields.NameNetworkNodeUsage, TypeSuffix: fields.TypeSuffixNetworkNodeUsage, Embedded: true},
 	}
 }
 
+// Managed returns an Object matcher that returns true if the supplied Object is
+// a ndd managed resource.
+func Managed() Object { return ManagedShape().Matches() }
+
+// ManagedList returns an Object matcher that returns true if the supplied
+// Object is a list of ndd managed resource.
+func ManagedList() Object { return ManagedListShape().Matches() }
+
+// NetworkNode returns an Object matcher that returns true if the supplied
+// Object is a NetworkNode.
+func NetworkNode() Object { return NetworkNodeShape().Matches() }
+
+// NetworkNodeUsage returns an Object matcher that returns true if the supplied
+// Object is a NetworkNodeUsage.
+func NetworkNodeUsage() Object { return NetworkNodeUsageShape().Matches() }
+
 // NetworkNodeUsageList returns an Object matcher that returns true if the
 // supplied Object is a list of NetworkNode usages.
-func NetworkNodeUsageList() Object {
-	return func(o types.Object) bool {
-		return fields.Has(o,
-			fields.IsTypeMeta().And(fields.IsEmbedded()),
-			fields.IsItems().And(fields.IsSlice()).And(fields.HasFieldThat(
-				fields.IsTypeMeta().And(fields.IsEmbedded()),
-				fields.IsObjectMeta().And(fields.IsEmbedded()),
-				fields.IsNetworkNodeUsage().And(fields.IsEmbedded()),
-			)),
-		)
-	}
-}
+func NetworkNodeUsageList() Object { return NetworkNodeUsageListShape().Matches() }
 
 // HasMarker returns an Object matcher that returns true if the supplied Object
 // has a comment marker k with the value v. Comment markers are read from the
@@ -146,3 +184,17 @@ func AnyOf(match ...Object) Object {
 		return false
 	}
 }
+
+// Memoize returns an Object matcher that caches the result of the supplied
+// Object matcher for each Object. It is not safe for concurrent use.
+func Memoize(match Object) Object {
+	results := map[types.Object]bool{}
+	return func(o types.Object) bool {
+		if r, ok := results[o]; ok {
+			return r
+		}
+		r := match(o)
+		results[o] = r
+		return r
+	}
+}
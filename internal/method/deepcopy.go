@@ -0,0 +1,375 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"go/types"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+)
+
+// NewDeepCopyInto returns a New that writes a DeepCopyInto method for the
+// supplied Object to the supplied file. The supplied matcher determines the
+// other named types of the package for which deep copy methods are generated,
+// and that may therefore be deep copied by calling their DeepCopyInto method.
+// Use CanDeepCopy to determine whether the method can be written.
+func NewDeepCopyInto(receiver string, generated match.Object) New {
+	return func(f *jen.File, o types.Object) {
+		dc := &deepCopier{pkg: o.Pkg(), generated: generated}
+		body := dc.into(receiver, o)
+		if dc.err != nil {
+			return
+		}
+		f.Commentf("DeepCopyInto copies this %s into out. in must be non-nil.", o.Name())
+		f.Func().Params(dc.receiver(receiver, o)).Id("DeepCopyInto").Params(jen.Id("out").Op("*").Id(o.Name())).Block(body...)
+	}
+}
+
+// NewDeepCopy returns a New that writes a DeepCopy method for the supplied
+// Object to the supplied file.
+func NewDeepCopy(receiver string) New {
+	return func(f *jen.File, o types.Object) {
+		dc := &deepCopier{pkg: o.Pkg()}
+		out, ret := jen.Op("*").Id(o.Name()), jen.Id("out")
+		if !isStruct(o.Type()) {
+			out, ret = jen.Id(o.Name()), jen.Op("*").Id("out")
+		}
+		f.Commentf("DeepCopy returns a deep copy of this %s.", o.Name())
+		f.Func().Params(dc.receiver(receiver, o)).Id("DeepCopy").Params().Add(out).Block(
+			jen.If(jen.Id(receiver).Op("==").Nil()).Block(jen.Return(jen.Nil())),
+			jen.Id("out").Op(":=").New(jen.Id(o.Name())),
+			jen.Id(receiver).Dot("DeepCopyInto").Call(jen.Id("out")),
+			jen.Return(ret),
+		)
+	}
+}
+
+// NewDeepCopyObject returns a New that writes a DeepCopyObject method for the
+// supplied Object to the supplied file, if it is matched by the supplied
+// matcher. The method implements runtime.Object, which is defined by the
+// supplied runtime package.
+func NewDeepCopyObject(receiver, runtime string, object match.Object) New {
+	return func(f *jen.File, o types.Object) {
+		if !isStruct(o.Type()) || !object(o) {
+			return
+		}
+		f.Commentf("DeepCopyObject returns a deep copy of this %s as a runtime.Object.", o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("DeepCopyObject").Params().Qual(runtime, "Object").Block(
+			jen.If(jen.Id("c").Op(":=").Id(receiver).Dot("DeepCopy").Call(), jen.Id("c").Op("!=").Nil()).Block(
+				jen.Return(jen.Id("c")),
+			),
+			jen.Return(jen.Nil()),
+		)
+	}
+}
+
+// CanDeepCopy returns an error if a DeepCopyInto method cannot be written for
+// the supplied Object, for example because it has a field of a channel or
+// function type. The supplied matcher is that of NewDeepCopyInto.
+func CanDeepCopy(o types.Object, generated match.Object) error {
+	dc := &deepCopier{pkg: o.Pkg(), generated: generated}
+	dc.into("in", o)
+	return errors.Wrapf(dc.err, "cannot deep copy %s", o.Name())
+}
+
+// A deepCopier builds the statements that deep copy values of the types used
+// by a package. It records the first type it cannot deep copy.
+//
+// The statements it builds deep copy *in into *out, where in and out are
+// pointers in scope. They assume that *out is either the zero value or a
+// shallow copy of *in, so nil values need not be copied.
+type deepCopier struct {
+	pkg       *types.Package
+	generated match.Object
+	err       error
+}
+
+func (dc *deepCopier) fail(t types.Type, reason string) {
+	if dc.err == nil {
+		dc.err = errors.Errorf("%s %s", types.TypeString(t, types.RelativeTo(dc.pkg)), reason)
+	}
+}
+
+// receiver returns the receiver of the deep copy methods of the supplied
+// Object. Structs use pointer receivers, while slices and maps use value
+// receivers so that their methods may be called on non-addressable values.
+func (dc *deepCopier) receiver(receiver string, o types.Object) *jen.Statement {
+	if isStruct(o.Type()) {
+		return jen.Id(receiver).Op("*").Id(o.Name())
+	}
+	return jen.Id(receiver).Id(o.Name())
+}
+
+// into returns the body of the DeepCopyInto method of the supplied Object.
+func (dc *deepCopier) into(receiver string, o types.Object) []jen.Code {
+	u := o.Type().Underlying()
+	switch u.(type) {
+	case *types.Struct:
+		return dc.body(receiver, u)
+	case *types.Slice, *types.Map:
+		return []jen.Code{jen.Block(append([]jen.Code{jen.Id("in").Op(":=").Op("&").Id(receiver)}, dc.body("in", u)...)...)}
+	}
+	dc.fail(o.Type(), "is not a struct, slice or map")
+	return nil
+}
+
+// body returns the statements that deep copy *in into *out, where both are of
+// the supplied type. The supplied in identifier is used in place of in.
+func (dc *deepCopier) body(in string, t types.Type) []jen.Code {
+	deref := func() *jen.Statement { return jen.Op("*").Id(in) }
+	if dc.shallow(t) {
+		return []jen.Code{jen.Op("*").Id("out").Op("=").Add(deref())}
+	}
+	if dc.hasDeepCopyInto(t) {
+		return []jen.Code{jen.Parens(deref()).Dot("DeepCopyInto").Call(jen.Id("out"))}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		e := u.Elem()
+		stmts := []jen.Code{jen.Op("*").Id("out").Op("=").New(dc.typeCode(e))}
+		switch {
+		case dc.shallow(e):
+			return append(stmts, jen.Op("**").Id("out").Op("=").Op("**").Id(in))
+		case dc.hasDeepCopyInto(e):
+			return append(stmts, jen.Parens(deref()).Dot("DeepCopyInto").Call(jen.Op("*").Id("out")))
+		}
+		return append(stmts, dc.copy(
+			func() *jen.Statement { return jen.Op("**").Id(in) },
+			func() *jen.Statement { return jen.Op("**").Id("out") },
+			e)...)
+
+	case *types.Slice:
+		stmts := []jen.Code{jen.Op("*").Id("out").Op("=").Make(dc.typeCode(t), jen.Len(deref()))}
+		if dc.shallow(u.Elem()) {
+			return append(stmts, jen.Copy(jen.Op("*").Id("out"), deref()))
+		}
+		return append(stmts, jen.For(jen.Id("i").Op(":=").Range().Add(deref())).Block(
+			dc.copy(
+				func() *jen.Statement { return jen.Parens(deref()).Index(jen.Id("i")) },
+				func() *jen.Statement { return jen.Parens(jen.Op("*").Id("out")).Index(jen.Id("i")) },
+				u.Elem())...,
+		))
+
+	case *types.Array:
+		if dc.shallow(u.Elem()) {
+			return []jen.Code{jen.Op("*").Id("out").Op("=").Add(deref())}
+		}
+		return []jen.Code{
+			jen.Op("*").Id("out").Op("=").Add(deref()),
+			jen.For(jen.Id("i").Op(":=").Range().Add(deref())).Block(
+				dc.copy(
+					func() *jen.Statement { return jen.Parens(deref()).Index(jen.Id("i")) },
+					func() *jen.Statement { return jen.Parens(jen.Op("*").Id("out")).Index(jen.Id("i")) },
+					u.Elem())...,
+			),
+		}
+
+	case *types.Map:
+		if !dc.shallow(u.Key()) {
+			dc.fail(t, "has a key that cannot be copied by assignment")
+			return nil
+		}
+		return []jen.Code{
+			jen.Op("*").Id("out").Op("=").Make(dc.typeCode(t), jen.Len(deref())),
+			jen.For(jen.List(jen.Id("key"), jen.Id("val")).Op(":=").Range().Add(deref())).Block(dc.mapValue(u.Elem())...),
+		}
+
+	case *types.Struct:
+		stmts := []jen.Code{jen.Op("*").Id("out").Op("=").Add(deref())}
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if dc.shallow(f.Type()) {
+				continue
+			}
+			if !f.Exported() && f.Pkg() != dc.pkg {
+				dc.fail(t, "has unexported field "+f.Name())
+				return nil
+			}
+			name := f.Name()
+			stmts = append(stmts, dc.copy(
+				func() *jen.Statement { return jen.Id(in).Dot(name) },
+				func() *jen.Statement { return jen.Id("out").Dot(name) },
+				f.Type())...)
+		}
+		return stmts
+
+	case *types.Interface:
+		n, ok := t.(*types.Named)
+		if !ok {
+			dc.fail(t, "is an unnamed interface")
+			return nil
+		}
+		if !hasMethod(n, "DeepCopy"+n.Obj().Name()) {
+			dc.fail(t, "has no DeepCopy"+n.Obj().Name()+" method")
+			return nil
+		}
+		return []jen.Code{jen.Op("*").Id("out").Op("=").Parens(deref()).Dot("DeepCopy" + n.Obj().Name()).Call()}
+	}
+
+	dc.fail(t, "cannot be deep copied")
+	return nil
+}
+
+// copy returns the statements that deep copy the supplied addressable source
+// into the supplied addressable destination, where both are of the supplied
+// type. The supplied type must not be shallow.
+func (dc *deepCopier) copy(src, dst func() *jen.Statement, t types.Type) []jen.Code {
+	shadow := jen.List(jen.Id("in"), jen.Id("out")).Op(":=").List(jen.Op("&").Add(src()), jen.Op("&").Add(dst()))
+	switch {
+	case nilable(t):
+		return []jen.Code{jen.If(src().Op("!=").Nil()).Block(append([]jen.Code{shadow}, dc.body("in", t)...)...)}
+	case dc.hasDeepCopyInto(t):
+		return []jen.Code{src().Dot("DeepCopyInto").Call(jen.Op("&").Add(dst()))}
+	}
+	return []jen.Code{jen.Block(append([]jen.Code{shadow}, dc.body("in", t)...)...)}
+}
+
+// mapValue returns the statements that deep copy val into (*out)[key], where
+// val is of the supplied type.
+func (dc *deepCopier) mapValue(t types.Type) []jen.Code {
+	dst := jen.Parens(jen.Op("*").Id("out")).Index(jen.Id("key"))
+	switch {
+	case dc.shallow(t):
+		return []jen.Code{dst.Op("=").Id("val")}
+	case dc.hasDeepCopy(t) && isStruct(t):
+		return []jen.Code{dst.Op("=").Op("*").Id("val").Dot("DeepCopy").Call()}
+	case dc.hasDeepCopy(t):
+		return []jen.Code{dst.Op("=").Id("val").Dot("DeepCopy").Call()}
+	}
+	stmts := []jen.Code{jen.Var().Id("outVal").Add(dc.typeCode(t))}
+	stmts = append(stmts, dc.copy(
+		func() *jen.Statement { return jen.Id("val") },
+		func() *jen.Statement { return jen.Id("outVal") },
+		t)...)
+	return append(stmts, dst.Op("=").Id("outVal"))
+}
+
+// shallow returns true if values of the supplied type may be deep copied by
+// assignment. Structs from other packages that have unexported fields and no
+// DeepCopyInto method, such as time.Time, are assumed to be values that may
+// be copied by assignment.
+func (dc *deepCopier) shallow(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Kind() == types.Invalid {
+			dc.fail(t, "is not a valid type")
+		}
+		return true
+	case *types.Array:
+		return dc.shallow(u.Elem())
+	case *types.Struct:
+		if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != dc.pkg && hasUnexportedFields(u) && !hasMethod(n, "DeepCopyInto") {
+			return true
+		}
+		for i := 0; i < u.NumFields(); i++ {
+			if !dc.shallow(u.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// hasDeepCopyInto returns true if the supplied type is a named type that has,
+// or is about to have, a DeepCopyInto method.
+func (dc *deepCopier) hasDeepCopyInto(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	if n.Obj().Pkg() == dc.pkg && dc.generated != nil && dc.generated(n.Obj()) {
+		return true
+	}
+	return hasMethod(n, "DeepCopyInto")
+}
+
+// hasDeepCopy returns true if the supplied type is a named type that has, or
+// is about to have, DeepCopyInto and DeepCopy methods.
+func (dc *deepCopier) hasDeepCopy(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	if n.Obj().Pkg() == dc.pkg && dc.generated != nil && dc.generated(n.Obj()) {
+		return true
+	}
+	return hasMethod(n, "DeepCopyInto") && hasMethod(n, "DeepCopy")
+}
+
+// typeCode returns the supplied type as code.
+func (dc *deepCopier) typeCode(t types.Type) jen.Code {
+	switch u := t.(type) {
+	case *types.Named:
+		if u.Obj().Pkg() == nil || u.Obj().Pkg() == dc.pkg {
+			return jen.Id(u.Obj().Name())
+		}
+		return jen.Qual(u.Obj().Pkg().Path(), u.Obj().Name())
+	case *types.Basic:
+		return jen.Id(u.Name())
+	case *types.Pointer:
+		return jen.Op("*").Add(dc.typeCode(u.Elem()))
+	case *types.Slice:
+		return jen.Index().Add(dc.typeCode(u.Elem()))
+	case *types.Array:
+		return jen.Index(jen.Lit(int(u.Len()))).Add(dc.typeCode(u.Elem()))
+	case *types.Map:
+		return jen.Map(dc.typeCode(u.Key())).Add(dc.typeCode(u.Elem()))
+	case *types.Interface:
+		if u.Empty() {
+			return jen.Interface()
+		}
+	}
+	dc.fail(t, "cannot be referred to by name")
+	return jen.Null()
+}
+
+func nilable(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Interface:
+		return true
+	}
+	return false
+}
+
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func hasUnexportedFields(s *types.Struct) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if !s.Field(i).Exported() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMethod returns true if the supplied type has a method with the supplied
+// name. Methods promoted from embedded fields are not considered.
+func hasMethod(t types.Type, name string) bool {
+	if types.IsInterface(t) {
+		return types.NewMethodSet(t).Lookup(nil, name) != nil
+	}
+	sel := types.NewMethodSet(types.NewPointer(t)).Lookup(nil, name)
+	return sel != nil && len(sel.Index()) == 1
+}
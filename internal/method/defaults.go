@@ -0,0 +1,367 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+)
+
+// markerDefault sets the default value of a field, as it does in CRD schemas.
+const markerDefault = "kubebuilder:default"
+
+// NewDefault returns a NewMethod that writes a Default method for the supplied
+// Object to the supplied file. The method sets every unset field of the
+// Object's spec that has a +kubebuilder:default or comments.MarkerDefault
+// marker to its default value. Pointers, slices and maps are unset if they are
+// nil or empty, and other fields if they are zero. Fields are found in nested
+// structs, pointers to structs that are set or have a default of {}, the items
+// of slices, and the values of maps of pointers. Comments of the Object's
+// package are supplied. Local variables of the method are named so that they
+// do not shadow the supplied receiver.
+func NewDefault(receiver string, c comments.Comments) New {
+	return func(f *jen.File, o types.Object) {
+		d := &defaulter{comments: c, object: o, receiver: receiver, visiting: map[*types.TypeName]bool{}}
+		body := d.fields(jen.Id(receiver).Dot(fields.NameSpec), fieldType(o.Type(), fields.NameSpec), 0)
+
+		f.Commentf("Default sets the unset fields of the spec of this %s to their default values.", o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("Default").Params().BlockFunc(func(g *jen.Group) {
+			for _, c := range body {
+				g.Add(c)
+			}
+		})
+	}
+}
+
+// CheckDefaults returns an error describing every default marker of the spec
+// of the supplied Object whose value is not a valid value of its field.
+func CheckDefaults(c comments.Comments, o types.Object) error {
+	d := &defaulter{comments: c, object: o, visiting: map[*types.TypeName]bool{}}
+	d.fields(jen.Id(fields.NameSpec), fieldType(o.Type(), fields.NameSpec), 0)
+	if len(d.errs) > 0 {
+		return errors.New(strings.Join(d.errs, "; "))
+	}
+	return nil
+}
+
+// A defaulter writes the defaulting of the fields of an Object.
+type defaulter struct {
+	comments comments.Comments
+	object   types.Object
+	receiver string
+	visiting map[*types.TypeName]bool
+
+	// errs are the invalid markers that were found.
+	errs []string
+}
+
+// local returns the supplied name of a local variable, or a variant of it
+// that does not shadow the receiver.
+func (d *defaulter) local(name string) *jen.Statement {
+	if name == d.receiver {
+		name += "Default"
+	}
+	return jen.Id(name)
+}
+
+// fields returns the defaulting of the fields of the supplied value of the
+// supplied type, and of the values nested within them.
+func (d *defaulter) fields(val *jen.Statement, t types.Type, depth int) []jen.Code {
+	if t == nil {
+		return nil
+	}
+	if n, ok := t.(*types.Named); ok {
+		if d.visiting[n.Obj()] {
+			// Recursive types cannot be defaulted by straight line code.
+			return nil
+		}
+		d.visiting[n.Obj()] = true
+		defer delete(d.visiting, n.Obj())
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		var body []jen.Code
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if name, _ := jsonName(u.Tag(i)); name == "-" || !f.Exported() {
+				continue
+			}
+			fv := val.Clone().Dot(f.Name())
+			m := comments.ParseMarkers(d.comments.Field(f))
+			if n, ok := f.Type().(*types.Named); ok {
+				m = merge(m, comments.ParseMarkers(d.comments.For(n.Obj())))
+			}
+			body = append(body, d.field(fv, f, m, depth)...)
+		}
+		return body
+
+	case *types.Pointer:
+		nested := d.fields(deref(val, u.Elem()), u.Elem(), depth)
+		if len(nested) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.If(val.Clone().Op("!=").Nil()).Block(nested...)}
+
+	case *types.Slice:
+		i := d.local(fmt.Sprintf("i%d", depth))
+		nested := d.fields(val.Clone().Index(i.Clone()), u.Elem(), depth+1)
+		if len(nested) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.For(i.Clone().Op(":=").Range().Add(val.Clone())).Block(nested...)}
+
+	case *types.Map:
+		if _, ok := u.Elem().(*types.Pointer); !ok {
+			// The values of maps are not addressable.
+			return nil
+		}
+		v := d.local(fmt.Sprintf("v%d", depth))
+		nested := d.fields(v.Clone(), u.Elem(), depth+1)
+		if len(nested) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.For(jen.List(jen.Id("_"), v.Clone()).Op(":=").Range().Add(val.Clone())).Block(nested...)}
+	}
+	return nil
+}
+
+// field returns the defaulting of the supplied field value, per the supplied
+// markers, followed by that of the values nested within it.
+func (d *defaulter) field(val *jen.Statement, f *types.Var, m comments.Markers, depth int) []jen.Code {
+	raw, ok := defaultOf(m)
+	if !ok {
+		return d.fields(val, f.Type(), depth)
+	}
+	s := &sampler{pkg: d.object.Pkg()}
+
+	var unset *jen.Statement
+	switch u := f.Type().Underlying().(type) {
+	case *types.Pointer:
+		unset = val.Clone().Op("==").Nil()
+	case *types.Slice, *types.Map:
+		unset = jen.Len(val.Clone()).Op("==").Lit(0)
+	case *types.Struct:
+		// Structs are never unset, but their fields may be.
+		return d.fields(val, f.Type(), depth)
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			unset = jen.Op("!").Add(val.Clone())
+		case u.Info()&types.IsString != 0:
+			unset = val.Clone().Op("==").Lit("")
+		default:
+			unset = val.Clone().Op("==").Lit(0)
+		}
+	default:
+		d.errorf("field %s: +%s=%s: defaults of %s fields are not supported", f.Name(), markerDefault, raw, f.Type())
+		return nil
+	}
+
+	elem := f.Type()
+	if p, ok := elem.Underlying().(*types.Pointer); ok {
+		elem = p.Elem()
+	}
+	v, err := d.value(s, elem, raw)
+	if err != nil {
+		d.errorf("field %s: +%s=%s: %s", f.Name(), markerDefault, raw, err)
+		return nil
+	}
+	set := []jen.Code{val.Clone().Op("=").Add(v)}
+	if elem != f.Type() {
+		set = []jen.Code{val.Clone().Op("=").Op("&").Add(v)}
+		if _, ok := elem.Underlying().(*types.Struct); !ok {
+			// Only composite literals are addressable.
+			l := d.local("v")
+			set = []jen.Code{l.Clone().Op(":=").Add(v), val.Clone().Op("=").Op("&").Add(l)}
+		}
+	}
+
+	return append([]jen.Code{jen.If(unset).Block(set...)}, d.fields(val, f.Type(), depth)...)
+}
+
+// value returns the supplied default value, per the syntax of crd default
+// markers, as a value of the supplied type.
+func (d *defaulter) value(s *sampler, t types.Type, raw string) (jen.Code, error) {
+	switch t.Underlying().(type) {
+	case *types.Slice:
+		l := comments.List(raw)
+		items := make([]interface{}, 0, len(l))
+		for _, e := range l {
+			items = append(items, e)
+		}
+		return d.literal(s, t, items)
+	case *types.Map, *types.Struct:
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, errors.Errorf("%q is not an object", raw)
+		}
+		return d.literal(s, t, v)
+	}
+	return d.literal(s, t, raw)
+}
+
+// literal returns the supplied value, which may be parsed from YAML, as a
+// value of the supplied type. Scalars may be strings.
+func (d *defaulter) literal(s *sampler, t types.Type, v interface{}) (jen.Code, error) { // nolint:gocyclo
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		raw := fmt.Sprint(v)
+		var c jen.Code
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, errors.Errorf("%q is not a boolean", raw)
+			}
+			c = jen.Lit(b)
+		case u.Info()&types.IsString != 0:
+			c = jen.Lit(raw)
+		case u.Info()&types.IsNumeric != 0 && u.Info()&types.IsComplex == 0:
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, errors.Errorf("%q is not a number", raw)
+			}
+			if c, err = lit(f, u.Info()&types.IsInteger != 0); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.Errorf("unsupported type %s", t)
+		}
+		if b, ok := t.(*types.Basic); ok && (b.Kind() == types.Bool || b.Kind() == types.String || b.Kind() == types.Int || b.Kind() == types.Float64) {
+			return c, nil
+		}
+		return s.typeCode(t).Call(c), nil
+
+	case *types.Pointer:
+		return nil, errors.Errorf("unsupported type %s", t)
+
+	case *types.Slice:
+		l, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("%v is not a list", v)
+		}
+		items := make([]jen.Code, 0, len(l))
+		for _, e := range l {
+			c, err := d.literal(s, u.Elem(), e)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, c)
+		}
+		return s.typeCode(t).Values(items...), nil
+
+	case *types.Map:
+		m, err := object(v)
+		if err != nil {
+			return nil, err
+		}
+		dict := jen.Dict{}
+		for _, k := range sortedKeys(m) {
+			kc, err := d.literal(s, u.Key(), k)
+			if err != nil {
+				return nil, err
+			}
+			vc, err := d.literal(s, u.Elem(), m[k])
+			if err != nil {
+				return nil, err
+			}
+			dict[kc] = vc
+		}
+		return s.typeCode(t).Values(dict), nil
+
+	case *types.Struct:
+		m, err := object(v)
+		if err != nil {
+			return nil, err
+		}
+		dict := jen.Dict{}
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			name, _ := jsonName(u.Tag(i))
+			if name == "" {
+				name = f.Name()
+			}
+			fv, ok := m[name]
+			if !ok {
+				continue
+			}
+			delete(m, name)
+			c, err := d.literal(s, f.Type(), fv)
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %s", name)
+			}
+			dict[jen.Id(f.Name())] = c
+		}
+		if len(m) > 0 {
+			return nil, errors.Errorf("unknown fields %s", strings.Join(sortedKeys(m), ", "))
+		}
+		return s.typeCode(t).Values(dict), nil
+	}
+	return nil, errors.Errorf("unsupported type %s", t)
+}
+
+func (d *defaulter) errorf(format string, args ...interface{}) {
+	d.errs = append(d.errs, d.object.Name()+": "+fmt.Sprintf(format, args...))
+}
+
+// defaultOf returns the default value declared by the supplied markers, if
+// any. See comments.MarkerDefault.
+func defaultOf(m comments.Markers) (string, bool) {
+	for _, k := range []string{markerDefault, comments.MarkerDefault} {
+		if v := m[k]; len(v) > 0 {
+			return comments.Unquote(last(v)), true
+		}
+	}
+	return "", false
+}
+
+// object returns the supplied value, parsed from YAML, as an object. A nil
+// value is an empty object.
+func object(v interface{}) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	if v == nil {
+		return out, nil
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.Errorf("%v is not an object", v)
+	}
+	for k, e := range m {
+		out[fmt.Sprint(k)] = e
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// FieldDiff is the name of the type that describes a field whose value differs
+// between two objects. See WriteFieldDiff.
+const FieldDiff = "FieldDiff"
+
+// WriteFieldDiff writes the FieldDiff type returned by the Diff methods
+// written by NewDiff to the supplied file.
+func WriteFieldDiff(f *jen.File) {
+	f.Comment("A FieldDiff is a field whose value differs between two objects.")
+	f.Comment("+kubebuilder:object:generate=false")
+	f.Comment("+k8s:deepcopy-gen=false")
+	f.Type().Id(FieldDiff).Struct(
+		jen.Comment("Path of the field, e.g. spec.forProvider.name."),
+		jen.Id("Path").String(),
+		jen.Line(),
+		jen.Comment("Value of the field in the object whose Diff method was called."),
+		jen.Id("Value").Interface(),
+		jen.Line(),
+		jen.Comment("Other value of the field, in the object it was compared to."),
+		jen.Id("Other").Interface(),
+	)
+}
+
+// NewDiff returns a NewMethod that writes a Diff method for the supplied
+// Object, which must be a struct, to the supplied file. The method returns a
+// FieldDiff for every field of the Object whose value differs from that of
+// another, by JSON path relative to the supplied root path, e.g. spec. Nil and
+// empty slices and maps are equal. Slices of different lengths, and map
+// entries that are missing from either Object, differ as a whole. Values of
+// recursive types, interfaces, and structs with unexported fields of other
+// packages are compared using reflect.DeepEqual.
+func NewDiff(receiver, root string) New {
+	return func(f *jen.File, o types.Object) {
+		d := &differ{pkg: o.Pkg(), visiting: map[*types.TypeName]bool{}}
+		body := d.diff(jen.Id(receiver), jen.Id("other"), o.Type(), diffPath{format: root}, 0)
+
+		f.Commentf("Diff returns the fields of this %s whose values differ from those of the supplied %s.", o.Name(), o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("Diff").Params(jen.Id("other").Op("*").Id(o.Name())).Index().Id(FieldDiff).BlockFunc(func(g *jen.Group) {
+			g.If(jen.Id(receiver).Op("==").Nil().Op("||").Id("other").Op("==").Nil()).Block(
+				jen.If(jen.Id(receiver).Op("==").Id("other")).Block(jen.Return(jen.Nil())),
+				jen.Return(jen.Index().Id(FieldDiff).Values(jen.Values(jen.Dict{
+					jen.Id("Path"):  jen.Lit(root),
+					jen.Id("Value"): jen.Id(receiver),
+					jen.Id("Other"): jen.Id("other"),
+				}))),
+			)
+			g.Var().Id("diffs").Index().Id(FieldDiff)
+			for _, c := range body {
+				g.Add(c)
+			}
+			if d.unordered {
+				g.Qual("sort", "Slice").Call(jen.Id("diffs"), jen.Func().Params(jen.List(jen.Id("i"), jen.Id("j")).Int()).Bool().Block(
+					jen.Return(jen.Id("diffs").Index(jen.Id("i")).Dot("Path").Op("<").Id("diffs").Index(jen.Id("j")).Dot("Path")),
+				))
+			}
+			g.Return(jen.Id("diffs"))
+		})
+	}
+}
+
+// NewEqual returns a NewMethod that writes an Equal method for the supplied
+// Object to the supplied file. Objects are equal if their Diff is empty.
+func NewEqual(receiver string) New {
+	return func(f *jen.File, o types.Object) {
+		f.Commentf("Equal returns true if this %s is equal to the supplied %s.", o.Name(), o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("Equal").Params(jen.Id("other").Op("*").Id(o.Name())).Bool().Block(
+			jen.Return(jen.Len(jen.Id(receiver).Dot("Diff").Call(jen.Id("other"))).Op("==").Lit(0)),
+		)
+	}
+}
+
+// A diffPath is the JSON path of a field, as a format string and the
+// arguments that complete it.
+type diffPath struct {
+	format string
+	args   []jen.Code
+}
+
+func (p diffPath) child(name string) diffPath {
+	return diffPath{format: p.format + "." + name, args: p.args}
+}
+
+func (p diffPath) index(verb string, arg jen.Code) diffPath {
+	return diffPath{format: p.format + "[" + verb + "]", args: append(append([]jen.Code{}, p.args...), arg)}
+}
+
+func (p diffPath) code() jen.Code {
+	if len(p.args) == 0 {
+		return jen.Lit(p.format)
+	}
+	return jen.Qual("fmt", "Sprintf").Call(append([]jen.Code{jen.Lit(p.format)}, p.args...)...)
+}
+
+// A differ writes the comparison of the fields of two objects.
+type differ struct {
+	pkg      *types.Package
+	visiting map[*types.TypeName]bool
+
+	// unordered is true if the comparison iterates over a map, and thus
+	// produces FieldDiffs in no particular order.
+	unordered bool
+}
+
+// diff returns the comparison of the supplied values of the supplied type,
+// which are at the supplied path.
+func (d *differ) diff(a, b *jen.Statement, t types.Type, p diffPath, depth int) []jen.Code { // nolint:gocyclo
+	if n, ok := t.(*types.Named); ok {
+		if d.visiting[n.Obj()] {
+			// Recursive types cannot be compared by straight line code.
+			return d.deepEqual(a, b, p)
+		}
+		d.visiting[n.Obj()] = true
+		defer delete(d.visiting, n.Obj())
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return []jen.Code{jen.If(a.Clone().Op("!=").Add(b.Clone())).Block(d.differs(a, b, p))}
+
+	case *types.Pointer:
+		c := jen.If(jen.Parens(a.Clone().Op("==").Nil()).Op("!=").Parens(b.Clone().Op("==").Nil())).Block(d.differs(a, b, p))
+		if nested := d.diff(deref(a, u.Elem()), deref(b, u.Elem()), u.Elem(), p, depth); len(nested) > 0 {
+			c = c.Else().If(a.Clone().Op("!=").Nil()).Block(nested...)
+		}
+		return []jen.Code{c}
+
+	case *types.Slice:
+		if e, ok := u.Elem().(*types.Basic); ok && e.Kind() == types.Byte {
+			return []jen.Code{jen.If(jen.Op("!").Qual("bytes", "Equal").Call(a.Clone(), b.Clone())).Block(d.differs(a, b, p))}
+		}
+		i := jen.Id(fmt.Sprintf("i%d", depth))
+		c := jen.If(jen.Len(a.Clone()).Op("!=").Len(b.Clone())).Block(d.differs(a, b, p))
+		if nested := d.diff(a.Clone().Index(i.Clone()), b.Clone().Index(i.Clone()), u.Elem(), p.index("%d", i.Clone()), depth+1); len(nested) > 0 {
+			c = c.Else().Block(jen.For(i.Clone().Op(":=").Range().Add(a.Clone())).Block(nested...))
+		}
+		return []jen.Code{c}
+
+	case *types.Array:
+		i := jen.Id(fmt.Sprintf("i%d", depth))
+		nested := d.diff(a.Clone().Index(i.Clone()), b.Clone().Index(i.Clone()), u.Elem(), p.index("%d", i.Clone()), depth+1)
+		if len(nested) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.For(i.Clone().Op(":=").Range().Add(a.Clone())).Block(nested...)}
+
+	case *types.Map:
+		d.unordered = true
+		k, v, w := jen.Id(fmt.Sprintf("k%d", depth)), jen.Id(fmt.Sprintf("v%d", depth)), jen.Id(fmt.Sprintf("w%d", depth))
+		kp := p.index("%v", k.Clone())
+		missing := jen.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Add(b.Clone()).Index(k.Clone()), jen.Op("!").Id("ok")).Block(d.differs(v, jen.Nil(), kp))
+		if nested := d.diff(v.Clone(), w.Clone(), u.Elem(), kp, depth+1); len(nested) > 0 {
+			missing = jen.If(jen.List(w.Clone(), jen.Id("ok")).Op(":=").Add(b.Clone()).Index(k.Clone()), jen.Op("!").Id("ok")).Block(
+				d.differs(v, jen.Nil(), kp),
+			).Else().Block(nested...)
+		}
+		return []jen.Code{
+			jen.For(jen.List(k.Clone(), v.Clone()).Op(":=").Range().Add(a.Clone())).Block(missing),
+			jen.For(jen.List(k.Clone(), w.Clone()).Op(":=").Range().Add(b.Clone())).Block(
+				jen.If(jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Add(a.Clone()).Index(k.Clone()), jen.Op("!").Id("ok")).Block(
+					d.differs(jen.Nil(), w, kp),
+				),
+			),
+		}
+
+	case *types.Struct:
+		var body []jen.Code
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if !f.Exported() {
+				if f.Pkg() != d.pkg {
+					// Unexported fields of other packages cannot be compared
+					// field by field.
+					return d.deepEqual(a, b, p)
+				}
+				continue
+			}
+			name, opts := jsonName(u.Tag(i))
+			if name == "-" {
+				continue
+			}
+			fp := p
+			switch {
+			case opts["inline"] || (f.Embedded() && name == ""):
+			case name == "":
+				fp = p.child(f.Name())
+			default:
+				fp = p.child(name)
+			}
+			body = append(body, d.diff(a.Clone().Dot(f.Name()), b.Clone().Dot(f.Name()), f.Type(), fp, depth)...)
+		}
+		return body
+	}
+	return d.deepEqual(a, b, p)
+}
+
+func (d *differ) deepEqual(a, b *jen.Statement, p diffPath) []jen.Code {
+	return []jen.Code{jen.If(jen.Op("!").Qual("reflect", "DeepEqual").Call(a.Clone(), b.Clone())).Block(d.differs(a, b, p))}
+}
+
+func (d *differ) differs(a, b *jen.Statement, p diffPath) jen.Code {
+	return jen.Id("diffs").Op("=").Append(jen.Id("diffs"), jen.Id(FieldDiff).Values(jen.Dict{
+		jen.Id("Path"):  p.code(),
+		jen.Id("Value"): a.Clone(),
+		jen.Id("Other"): b.Clone(),
+	}))
+}
@@ -57,13 +57,14 @@ type Filter func(o types.Object, methodName string) bool
 // DefinedOutside returns a MethodFilter that returns true if the supplied
 // object has a method with the supplied name that is not defined in the
 // supplied filename. The object's filename is determined using the supplied
-// FileSet.
+// FileSet. Methods promoted from embedded fields are not considered, since a
+// method defined for the object itself takes precedence over them.
 func DefinedOutside(fs *token.FileSet, filename string) Filter {
 	return func(o types.Object, name string) bool {
 		s := types.NewMethodSet(types.NewPointer(o.Type()))
 		for i := 0; i < s.Len(); i++ {
 			mo := s.At(i).Obj()
-			if mo.Name() != name {
+			if mo.Name() != name || len(s.At(i).Index()) > 1 {
 				continue
 			}
 			if fs.Position(mo.Pos()).Filename != filename {
@@ -0,0 +1,259 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+)
+
+// NewComputeExternalLeafRefs returns a NewMethod that writes a
+// ComputeExternalLeafRefs method for the supplied Object to the supplied file.
+// The method returns a reference, e.g. /interface[name=ethernet-1/1], for the
+// value of every field of the Object's spec that is marked with
+// comments.MarkerLeafRef, sorted. Zero values are not referred to. Fields are
+// found in nested structs, and in the items of slices and maps, of types
+// declared by the Object's package, whose comments are supplied.
+func NewComputeExternalLeafRefs(receiver string, c comments.Comments) New {
+	return func(f *jen.File, o types.Object) {
+		w := &refWalker{comments: c, pkg: o.Pkg(), visiting: map[*types.TypeName]bool{}}
+		w.leaf = func(v *jen.Statement, t types.Type, _ string, m comments.Markers, _ bool) []jen.Code {
+			list, key, err := m.LeafRef()
+			if list == "" || err != nil {
+				return nil
+			}
+			return eachValue(v, t, 0, func(v *jen.Statement, t types.Type) jen.Code {
+				return jen.Id("refs").Op("=").Append(jen.Id("refs"), jen.Lit(list+"["+key+"=").Op("+").Add(str(v, t)).Op("+").Lit("]"))
+			})
+		}
+		body := w.walk(jen.Id(receiver).Dot(fields.NameSpec), fieldType(o.Type(), fields.NameSpec), 0, false)
+
+		f.Commentf("ComputeExternalLeafRefs of this %s, computed from its spec.", o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("ComputeExternalLeafRefs").Params().Index().String().BlockFunc(func(g *jen.Group) {
+			if len(body) == 0 {
+				g.Return(jen.Nil())
+				return
+			}
+			g.Var().Id("refs").Index().String()
+			for _, c := range body {
+				g.Add(c)
+			}
+			g.Qual("sort", "Strings").Call(jen.Id("refs"))
+			g.Return(jen.Id("refs"))
+		})
+	}
+}
+
+// NewComputeResourceIndexes returns a NewMethod that writes a
+// ComputeResourceIndexes method for the supplied Object to the supplied file.
+// The method returns the value of every field of the Object's spec that is
+// marked with comments.MarkerKey, keyed by its JSON name. Zero values are not
+// included. Fields are found in nested structs of types declared by the
+// Object's package, whose comments are supplied, but not in the items of
+// slices and maps.
+func NewComputeResourceIndexes(receiver string, c comments.Comments) New {
+	return func(f *jen.File, o types.Object) {
+		w := &refWalker{comments: c, pkg: o.Pkg(), visiting: map[*types.TypeName]bool{}}
+		w.leaf = func(v *jen.Statement, t types.Type, name string, m comments.Markers, inList bool) []jen.Code {
+			if !m.IsKey() || inList {
+				return nil
+			}
+			return ifSet(v, t, func(v *jen.Statement, t types.Type) jen.Code {
+				return jen.Id("idx").Index(jen.Lit(name)).Op("=").Add(str(v, t))
+			})
+		}
+		body := w.walk(jen.Id(receiver).Dot(fields.NameSpec), fieldType(o.Type(), fields.NameSpec), 0, false)
+
+		f.Commentf("ComputeResourceIndexes of this %s, computed from its spec.", o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("ComputeResourceIndexes").Params().Map(jen.String()).String().BlockFunc(func(g *jen.Group) {
+			g.Id("idx").Op(":=").Map(jen.String()).String().Values()
+			for _, c := range body {
+				g.Add(c)
+			}
+			g.Return(jen.Id("idx"))
+		})
+	}
+}
+
+// CheckReferences returns an error describing every invalid
+// comments.MarkerLeafRef marker of the spec of the supplied Object.
+func CheckReferences(c comments.Comments, o types.Object) error {
+	var errs []string
+	w := &refWalker{comments: c, pkg: o.Pkg(), visiting: map[*types.TypeName]bool{}}
+	w.leaf = func(_ *jen.Statement, _ types.Type, name string, m comments.Markers, _ bool) []jen.Code {
+		if _, _, err := m.LeafRef(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s field %s: %s", o.Name(), name, err))
+		}
+		return nil
+	}
+	w.walk(jen.Id(fields.NameSpec), fieldType(o.Type(), fields.NameSpec), 0, false)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// A refWalker walks the fields of a struct, writing the statements returned
+// by its leaf function for each of them.
+type refWalker struct {
+	comments comments.Comments
+	pkg      *types.Package
+	visiting map[*types.TypeName]bool
+
+	// leaf returns the statements for the supplied field value, which is of
+	// the supplied type and has the supplied JSON name and markers. inList is
+	// true if the field belongs to an item of a slice or map.
+	leaf func(v *jen.Statement, t types.Type, name string, m comments.Markers, inList bool) []jen.Code
+}
+
+// walk returns the statements for the supplied value of the supplied type,
+// and the values nested within it.
+func (w *refWalker) walk(v *jen.Statement, t types.Type, depth int, inList bool) []jen.Code {
+	switch u := t.(type) {
+	case *types.Named:
+		if u.Obj().Pkg() != w.pkg || w.visiting[u.Obj()] {
+			// Only the comments of the Object's package are known, and
+			// recursive types cannot be walked by straight line code.
+			return nil
+		}
+		w.visiting[u.Obj()] = true
+		defer delete(w.visiting, u.Obj())
+		return w.walk(v, u.Underlying(), depth, inList)
+
+	case *types.Pointer:
+		body := w.walk(deref(v, u.Elem()), u.Elem(), depth, inList)
+		if len(body) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.If(v.Clone().Op("!=").Nil()).Block(body...)}
+
+	case *types.Slice:
+		return w.each(v, u.Elem(), depth)
+
+	case *types.Array:
+		return w.each(v, u.Elem(), depth)
+
+	case *types.Map:
+		return w.each(v, u.Elem(), depth)
+
+	case *types.Struct:
+		var body []jen.Code
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			name := strings.Split(reflect.StructTag(u.Tag(i)).Get("json"), ",")[0]
+			if name == "-" || !f.Exported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name()
+			}
+			fv := v.Clone().Dot(f.Name())
+			body = append(body, w.leaf(fv, f.Type(), name, comments.ParseMarkers(w.comments.Field(f)), inList)...)
+			body = append(body, w.walk(fv, f.Type(), depth, inList)...)
+		}
+		return body
+	}
+	return nil
+}
+
+// each returns the statements for each item of the supplied slice, array or
+// map value.
+func (w *refWalker) each(v *jen.Statement, elem types.Type, depth int) []jen.Code {
+	item := jen.Id(fmt.Sprintf("v%d", depth))
+	body := w.walk(item, elem, depth+1, true)
+	if len(body) == 0 {
+		return nil
+	}
+	return []jen.Code{jen.For(jen.List(jen.Id("_"), item.Clone()).Op(":=").Range().Add(v.Clone())).Block(body...)}
+}
+
+// eachValue returns the statement written by the supplied function for the
+// supplied value if it is set, or for each of its items that are set if it is
+// a slice or array.
+func eachValue(v *jen.Statement, t types.Type, depth int, fn func(v *jen.Statement, t types.Type) jen.Code) []jen.Code {
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		item := jen.Id(fmt.Sprintf("r%d", depth))
+		return []jen.Code{jen.For(jen.List(jen.Id("_"), item.Clone()).Op(":=").Range().Add(v.Clone())).Block(eachValue(item, u.Elem(), depth+1, fn)...)}
+	case *types.Array:
+		item := jen.Id(fmt.Sprintf("r%d", depth))
+		return []jen.Code{jen.For(jen.List(jen.Id("_"), item.Clone()).Op(":=").Range().Add(v.Clone())).Block(eachValue(item, u.Elem(), depth+1, fn)...)}
+	}
+	return ifSet(v, t, fn)
+}
+
+// ifSet returns the statement written by the supplied function for the
+// supplied value of a basic type, or pointer to one, if it is set. Pointers
+// are set if they are not nil, and other values if they are not zero.
+func ifSet(v *jen.Statement, t types.Type, fn func(v *jen.Statement, t types.Type) jen.Code) []jen.Code {
+	if p, ok := t.(*types.Pointer); ok {
+		b, ok := p.Elem().Underlying().(*types.Basic)
+		if !ok {
+			return nil
+		}
+		if b.Info()&(types.IsBoolean|types.IsNumeric|types.IsString) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.If(v.Clone().Op("!=").Nil()).Block(fn(jen.Op("*").Add(v.Clone()), p.Elem()))}
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return nil
+	}
+	switch {
+	case b.Info()&types.IsBoolean != 0:
+		return []jen.Code{jen.If(v.Clone()).Block(fn(v, t))}
+	case b.Info()&types.IsString != 0:
+		return []jen.Code{jen.If(v.Clone().Op("!=").Lit("")).Block(fn(v, t))}
+	case b.Info()&types.IsNumeric != 0:
+		return []jen.Code{jen.If(v.Clone().Op("!=").Lit(0)).Block(fn(v, t))}
+	}
+	return nil
+}
+
+// deref returns the value the supplied pointer value points to, which is of
+// the supplied type. Pointers to structs are not dereferenced, since their
+// fields may be selected directly.
+func deref(v *jen.Statement, elem types.Type) *jen.Statement {
+	switch elem.Underlying().(type) {
+	case *types.Struct:
+		return v
+	case *types.Pointer:
+		return jen.Parens(jen.Op("*").Add(v.Clone()))
+	}
+	return jen.Op("*").Add(v.Clone())
+}
+
+// str returns the supplied value of the supplied basic type as a string.
+func str(v *jen.Statement, t types.Type) jen.Code {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok || b.Info()&types.IsString == 0 {
+		return jen.Qual("fmt", "Sprint").Call(v)
+	}
+	if _, named := t.(*types.Named); named {
+		return jen.String().Call(v)
+	}
+	return v
+}
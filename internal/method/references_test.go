@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+)
+
+// keys declares a managed resource whose spec has a key of each basic kind,
+// and of a pointer to each.
+const keys = `package v1
+
+type Mode string
+
+type ThingSpec struct {
+	// +ndd:key
+	Enabled bool ` + "`json:\"enabled\"`" + `
+	// +ndd:key
+	Name string ` + "`json:\"name\"`" + `
+	// +ndd:key
+	Mode Mode ` + "`json:\"mode\"`" + `
+	// +ndd:key
+	Index int32 ` + "`json:\"index\"`" + `
+	// +ndd:key
+	Weight float64 ` + "`json:\"weight\"`" + `
+	// +ndd:key
+	Shared *bool ` + "`json:\"shared\"`" + `
+	// +ndd:key
+	Alias *string ` + "`json:\"alias\"`" + `
+	// +ndd:key
+	Vlan *uint16 ` + "`json:\"vlan\"`" + `
+}
+
+type Thing struct {
+	Spec ThingSpec ` + "`json:\"spec\"`" + `
+}
+`
+
+const keysIndexes = `package v1
+
+import "fmt"
+
+// ComputeResourceIndexes of this Thing, computed from its spec.
+func (mg *Thing) ComputeResourceIndexes() map[string]string {
+	idx := map[string]string{}
+	if mg.Spec.Enabled {
+		idx["enabled"] = fmt.Sprint(mg.Spec.Enabled)
+	}
+	if mg.Spec.Name != "" {
+		idx["name"] = mg.Spec.Name
+	}
+	if mg.Spec.Mode != "" {
+		idx["mode"] = string(mg.Spec.Mode)
+	}
+	if mg.Spec.Index != 0 {
+		idx["index"] = fmt.Sprint(mg.Spec.Index)
+	}
+	if mg.Spec.Weight != 0 {
+		idx["weight"] = fmt.Sprint(mg.Spec.Weight)
+	}
+	if mg.Spec.Shared != nil {
+		idx["shared"] = fmt.Sprint(*mg.Spec.Shared)
+	}
+	if mg.Spec.Alias != nil {
+		idx["alias"] = *mg.Spec.Alias
+	}
+	if mg.Spec.Vlan != nil {
+		idx["vlan"] = fmt.Sprint(*mg.Spec.Vlan)
+	}
+	return idx
+}
+`
+
+// load type checks the supplied source of a package that imports nothing.
+func load(t *testing.T, src string) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "types.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := (&types.Config{}).Check("example.org/v1", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packages.Package{PkgPath: p.Path(), Name: p.Name(), Fset: fset, Syntax: []*ast.File{f}, Types: p}
+}
+
+func TestNewComputeResourceIndexes(t *testing.T) {
+	p := load(t, keys)
+	f := jen.NewFile(p.Name)
+	NewComputeResourceIndexes("mg", comments.In(p))(f, p.Types.Scope().Lookup("Thing"))
+
+	got := fmt.Sprintf("%#v", f)
+	if got != keysIndexes {
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{A: difflib.SplitLines(keysIndexes), B: difflib.SplitLines(got), FromFile: "want", ToFile: "got", Context: 3})
+		t.Errorf("NewComputeResourceIndexes(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"go/types"
+	"sort"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+)
+
+// maxSampleDepth limits how deeply nested sample values are.
+const maxSampleDepth = 4
+
+// A Test of one or more methods of an object.
+type Test struct {
+	// Methods tested by the test.
+	Methods []string
+
+	// New writes the test of the supplied object to the supplied file.
+	New New
+}
+
+// A TestSet is a map of test names to the Tests that produce them. Tests are
+// written as Test<Object><Name>.
+type TestSet map[string]Test
+
+// Write the TestSet for the supplied Object to the supplied file. A test is
+// not written if the supplied Filter filters any of the methods it tests.
+func (s TestSet) Write(f *jen.File, o types.Object, mf Filter) {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+NextTest:
+	for _, name := range names {
+		for _, m := range s[name].Methods {
+			if mf(o, m) {
+				continue NextTest
+			}
+		}
+		s[name].New(f, o)
+	}
+}
+
+// NewAccessorTest returns a Test of the supplied setter and getter of the
+// field at the supplied path. The test sets a sample value on a zero value of
+// the supplied Object and expects the getter to return it.
+func NewAccessorTest(receiver, set, get string, path ...string) Test {
+	return Test{
+		Methods: []string{set, get},
+		New: func(f *jen.File, o types.Object) {
+			ft := fieldType(o.Type(), path...)
+			if ft == nil {
+				return
+			}
+			s := &sampler{pkg: o.Pkg()}
+			f.Commentf("Test%s%s tests that %s returns the value set by %s.", o.Name(), set[len("Set"):], get, set)
+			f.Func().Id("Test"+o.Name()+set[len("Set"):]).Params(jen.Id("t").Op("*").Qual("testing", "T")).Block(
+				jen.Id(receiver).Op(":=").Op("&").Id(o.Name()).Values(),
+				jen.Id("want").Op(":=").Add(s.value(ft, 0)),
+				jen.Id(receiver).Dot(set).Call(jen.Id("want")),
+				jen.If(
+					jen.Id("got").Op(":=").Id(receiver).Dot(get).Call(),
+					jen.Op("!").Qual("reflect", "DeepEqual").Call(jen.Id("want"), jen.Id("got")),
+				).Block(
+					jen.Id("t").Dot("Errorf").Call(jen.Lit(get+"(): want %v, got %v"), jen.Id("want"), jen.Id("got")),
+				),
+			)
+		},
+	}
+}
+
+// NewConditionsTest returns a Test of the SetConditions and GetCondition
+// methods of the supplied Object. The test sets a sample condition on a zero
+// value of the Object and expects GetCondition to return it by its kind.
+func NewConditionsTest(receiver string) Test {
+	return Test{
+		Methods: []string{"SetConditions", "GetCondition"},
+		New: func(f *jen.File, o types.Object) {
+			ct := fieldType(o.Type(), fields.NameStatus, "Conditions")
+			if ct == nil {
+				return
+			}
+			st, ok := ct.Underlying().(*types.Slice)
+			if !ok {
+				return
+			}
+			s := &sampler{pkg: o.Pkg()}
+			f.Commentf("Test%sConditions tests that GetCondition returns the condition set by SetConditions.", o.Name())
+			f.Func().Id("Test"+o.Name()+"Conditions").Params(jen.Id("t").Op("*").Qual("testing", "T")).Block(
+				jen.Id(receiver).Op(":=").Op("&").Id(o.Name()).Values(),
+				jen.Id("want").Op(":=").Add(s.value(st.Elem(), 0)),
+				jen.Id(receiver).Dot("SetConditions").Call(jen.Id("want")),
+				jen.If(
+					jen.Id("got").Op(":=").Id(receiver).Dot("GetCondition").Call(jen.Id("want").Dot("Kind")),
+					jen.Op("!").Qual("reflect", "DeepEqual").Call(jen.Id("want"), jen.Id("got")),
+				).Block(
+					jen.Id("t").Dot("Errorf").Call(jen.Lit("GetCondition(): want %v, got %v"), jen.Id("want"), jen.Id("got")),
+				),
+			)
+		},
+	}
+}
+
+// NewGetItemsTest returns a Test of the GetItems method of the supplied list
+// Object. The test expects GetItems to return a pointer to each of its items.
+func NewGetItemsTest(receiver string) Test {
+	return Test{
+		Methods: []string{"GetItems"},
+		New: func(f *jen.File, o types.Object) {
+			it := fieldType(o.Type(), fields.NameItems)
+			if it == nil {
+				return
+			}
+			s := &sampler{pkg: o.Pkg()}
+			f.Commentf("Test%sGetItems tests that GetItems returns a pointer to each item.", o.Name())
+			f.Func().Id("Test"+o.Name()+"GetItems").Params(jen.Id("t").Op("*").Qual("testing", "T")).Block(
+				jen.Id(receiver).Op(":=").Op("&").Id(o.Name()).Values(jen.Dict{
+					jen.Id(fields.NameItems): jen.Make(s.typeCode(it), jen.Lit(2)),
+				}),
+				jen.Id("items").Op(":=").Id(receiver).Dot("GetItems").Call(),
+				jen.If(jen.Len(jen.Id("items")).Op("!=").Len(jen.Id(receiver).Dot(fields.NameItems))).Block(
+					jen.Id("t").Dot("Fatalf").Call(jen.Lit("GetItems(): want %d items, got %d"), jen.Len(jen.Id(receiver).Dot(fields.NameItems)), jen.Len(jen.Id("items"))),
+				),
+				jen.For(jen.Id("i").Op(":=").Range().Id("items")).Block(
+					jen.If(jen.Id("items").Index(jen.Id("i")).Op("!=").Op("&").Id(receiver).Dot(fields.NameItems).Index(jen.Id("i"))).Block(
+						jen.Id("t").Dot("Errorf").Call(jen.Lit("GetItems()[%d]: want a pointer to Items[%d]"), jen.Id("i"), jen.Id("i")),
+					),
+				),
+			)
+		},
+	}
+}
+
+// fieldType returns the type of the field at the supplied path of the supplied
+// type, or nil if there is no such field. Fields promoted from embedded
+// structs are found.
+func fieldType(t types.Type, path ...string) types.Type {
+	for _, name := range path {
+		o, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+		v, ok := o.(*types.Var)
+		if !ok || !v.IsField() {
+			return nil
+		}
+		t = v.Type()
+	}
+	return t
+}
+
+// A sampler writes non-zero sample values of types for use by tests.
+type sampler struct {
+	pkg *types.Package
+}
+
+// value returns a non-zero value of the supplied type, where possible. Values
+// of basic types other than those of untyped constants' default types are
+// converted to the supplied type so that the value's type may be inferred.
+func (s *sampler) value(t types.Type, depth int) jen.Code {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if b, ok := t.(*types.Basic); ok {
+			switch b.Kind() {
+			case types.Bool, types.String, types.Int, types.Float64:
+				return s.basic(u)
+			}
+		}
+		return s.typeCode(t).Call(s.basic(u))
+	case *types.Pointer:
+		if _, ok := u.Elem().Underlying().(*types.Struct); ok {
+			return jen.Op("&").Add(s.value(u.Elem(), depth))
+		}
+		return jen.Func().Params().Add(s.typeCode(t)).Block(
+			jen.Id("v").Op(":=").Add(s.value(u.Elem(), depth)),
+			jen.Return(jen.Op("&").Id("v")),
+		).Call()
+	case *types.Slice:
+		if depth >= maxSampleDepth {
+			return s.typeCode(t).Values()
+		}
+		return s.typeCode(t).Values(s.value(u.Elem(), depth+1))
+	case *types.Array:
+		// Only the first element of an array is set.
+		if depth >= maxSampleDepth || u.Len() == 0 {
+			return s.typeCode(t).Values()
+		}
+		return s.typeCode(t).Values(s.value(u.Elem(), depth+1))
+	case *types.Map:
+		if depth >= maxSampleDepth {
+			return s.typeCode(t).Values()
+		}
+		return s.typeCode(t).Values(jen.Dict{s.value(u.Key(), depth+1): s.value(u.Elem(), depth+1)})
+	case *types.Struct:
+		if depth >= maxSampleDepth {
+			return s.typeCode(t).Values()
+		}
+		d := jen.Dict{}
+		for i := 0; i < u.NumFields(); i++ {
+			fv := u.Field(i)
+			if !fv.Exported() || !s.sampleable(fv.Type()) {
+				continue
+			}
+			d[jen.Id(fv.Name())] = s.value(fv.Type(), depth+1)
+		}
+		return s.typeCode(t).Values(d)
+	}
+	return jen.Nil()
+}
+
+// sampleable returns true if the supplied type has sample values.
+func (s *sampler) sampleable(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&(types.IsBoolean|types.IsNumeric|types.IsString) != 0 && u.Info()&types.IsComplex == 0
+	case *types.Pointer:
+		return s.sampleable(u.Elem())
+	case *types.Array:
+		return s.sampleable(u.Elem())
+	case *types.Slice, *types.Map, *types.Struct:
+		return true
+	}
+	return false
+}
+
+func (s *sampler) basic(b *types.Basic) jen.Code {
+	switch {
+	case b.Info()&types.IsBoolean != 0:
+		return jen.True()
+	case b.Info()&types.IsString != 0:
+		return jen.Lit("test")
+	case b.Info()&types.IsFloat != 0:
+		return jen.Lit(1.5)
+	case b.Info()&types.IsNumeric != 0:
+		return jen.Lit(1)
+	}
+	return jen.Nil()
+}
+
+func (s *sampler) typeCode(t types.Type) *jen.Statement {
+	switch u := t.(type) {
+	case *types.Named:
+		if u.Obj().Pkg() == nil || u.Obj().Pkg() == s.pkg {
+			return jen.Id(u.Obj().Name())
+		}
+		return jen.Qual(u.Obj().Pkg().Path(), u.Obj().Name())
+	case *types.Basic:
+		return jen.Id(u.Name())
+	case *types.Pointer:
+		return jen.Op("*").Add(s.typeCode(u.Elem()))
+	case *types.Slice:
+		return jen.Index().Add(s.typeCode(u.Elem()))
+	case *types.Array:
+		return jen.Index(jen.Lit(int(u.Len()))).Add(s.typeCode(u.Elem()))
+	case *types.Map:
+		return jen.Map(s.typeCode(u.Key())).Add(s.typeCode(u.Elem()))
+	}
+	return jen.Interface()
+}
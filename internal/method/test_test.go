@@ -0,0 +1,76 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"fmt"
+	"go/types"
+	"testing"
+)
+
+const arrays = `package v1
+
+type Address [4]byte
+
+type Pair [2]string
+
+type ThingSpec struct {
+	Address Address
+	Bytes   [4]byte
+	Names   Pair
+	Matrix  [2][3]int
+	Empty   [0]int
+	Chans   [2]chan int
+}
+`
+
+func TestSamplerArrays(t *testing.T) {
+	p := load(t, arrays)
+	spec := p.Types.Scope().Lookup("ThingSpec").Type().Underlying().(*types.Struct)
+	s := &sampler{pkg: p.Types}
+
+	cases := map[string]struct {
+		sampleable bool
+		want       string
+	}{
+		"Address": {sampleable: true, want: "Address{byte(1)}"},
+		"Bytes":   {sampleable: true, want: "[4]byte{byte(1)}"},
+		"Names":   {sampleable: true, want: "Pair{\"test\"}"},
+		"Matrix":  {sampleable: true, want: "[2][3]int{[3]int{1}}"},
+		"Empty":   {sampleable: true, want: "[0]int{}"},
+		"Chans":   {sampleable: false},
+	}
+
+	for i := 0; i < spec.NumFields(); i++ {
+		f := spec.Field(i)
+		tc, ok := cases[f.Name()]
+		if !ok {
+			t.Fatalf("no case for field %s", f.Name())
+		}
+		t.Run(f.Name(), func(t *testing.T) {
+			if got := s.sampleable(f.Type()); got != tc.sampleable {
+				t.Fatalf("sampleable(%s): want %t, got %t", f.Type(), tc.sampleable, got)
+			}
+			if !tc.sampleable {
+				return
+			}
+			if got := fmt.Sprintf("%#v", s.value(f.Type(), 0)); got != tc.want {
+				t.Errorf("value(%s): want %s, got %s", f.Type(), tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package method
+
+import (
+	"fmt"
+	"go/types"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/pkg/errors"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+)
+
+// FieldPath is the import path of the Kubernetes API machinery package that
+// describes errors of the fields of an object.
+const FieldPath = "k8s.io/apimachinery/pkg/util/validation/field"
+
+// Validation markers.
+const (
+	markerMinimum          = "kubebuilder:validation:Minimum"
+	markerMaximum          = "kubebuilder:validation:Maximum"
+	markerExclusiveMinimum = "kubebuilder:validation:ExclusiveMinimum"
+	markerExclusiveMaximum = "kubebuilder:validation:ExclusiveMaximum"
+	markerMinLength        = "kubebuilder:validation:MinLength"
+	markerMaxLength        = "kubebuilder:validation:MaxLength"
+	markerMinItems         = "kubebuilder:validation:MinItems"
+	markerMaxItems         = "kubebuilder:validation:MaxItems"
+	markerPattern          = "kubebuilder:validation:Pattern"
+	markerEnum             = "kubebuilder:validation:Enum"
+)
+
+// NewValidate returns a NewMethod that writes a Validate method for the
+// supplied Object, which must be a struct, to the supplied file. The method
+// returns the aggregated errors of every field whose value violates its
+// validation markers, or those of its type. The kubebuilder Minimum, Maximum,
+// ExclusiveMinimum, ExclusiveMaximum, MinLength, MaxLength, MinItems,
+// MaxItems, Pattern and Enum markers are supported, as are
+// comments.MarkerRange and comments.MarkerLength. Markers of slice and map
+// fields other than MinItems and MaxItems apply to their items. Fields are
+// found in nested structs, and in the items of slices and maps. Comments of
+// the Object's package are supplied, and errors are reported relative to the
+// supplied root path, e.g. spec.
+func NewValidate(receiver, root string, c comments.Comments) New {
+	return func(f *jen.File, o types.Object) {
+		v := newValidator(c, o)
+		body := v.check(jen.Id(receiver), o.Type(), jen.Qual(FieldPath, "NewPath").Call(jen.Lit(root)), comments.Markers{}, 0)
+
+		for i, p := range v.patterns {
+			f.Var().Id(v.pattern(i)).Op("=").Qual("regexp", "MustCompile").Call(jen.Lit(p))
+		}
+		if len(v.patterns) > 0 {
+			f.Line()
+		}
+		f.Commentf("Validate this %s, per the validation markers of its fields.", o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("Validate").Params().Error().BlockFunc(func(g *jen.Group) {
+			g.Var().Id("errs").Qual(FieldPath, "ErrorList")
+			for _, c := range body {
+				g.Add(c)
+			}
+			g.Return(jen.Id("errs").Dot("ToAggregate").Call())
+		})
+	}
+}
+
+// CheckValidation returns an error describing every invalid validation marker
+// of the fields of the supplied Object.
+func CheckValidation(c comments.Comments, o types.Object) error {
+	v := newValidator(c, o)
+	v.check(jen.Id("v"), o.Type(), jen.Null(), comments.Markers{}, 0)
+	if len(v.errs) > 0 {
+		return errors.New(strings.Join(v.errs, "; "))
+	}
+	return nil
+}
+
+// A validator writes the validation of the fields of an Object.
+type validator struct {
+	comments comments.Comments
+	object   types.Object
+	visiting map[*types.TypeName]bool
+
+	// patterns used by the validation, compiled to package variables.
+	patterns []string
+
+	// errs are the invalid markers that were found.
+	errs []string
+}
+
+func newValidator(c comments.Comments, o types.Object) *validator {
+	return &validator{comments: c, object: o, visiting: map[*types.TypeName]bool{}}
+}
+
+// pattern returns the name of the variable of the supplied pattern.
+func (v *validator) pattern(i int) string {
+	return fmt.Sprintf("pattern%s%d", v.object.Name(), i)
+}
+
+// compile returns the index of the variable of the supplied pattern, adding
+// one if necessary.
+func (v *validator) compile(pattern string) int {
+	for i, p := range v.patterns {
+		if p == pattern {
+			return i
+		}
+	}
+	v.patterns = append(v.patterns, pattern)
+	return len(v.patterns) - 1
+}
+
+// check returns the validation of the supplied value of the supplied type,
+// which is at the supplied path and has the supplied markers, and of the
+// values nested within it.
+func (v *validator) check(val *jen.Statement, t types.Type, path *jen.Statement, m comments.Markers, depth int) []jen.Code {
+	if n, ok := t.(*types.Named); ok {
+		if v.visiting[n.Obj()] {
+			// Recursive types cannot be validated by straight line code.
+			return nil
+		}
+		v.visiting[n.Obj()] = true
+		defer delete(v.visiting, n.Obj())
+		m = merge(m, comments.ParseMarkers(v.comments.For(n.Obj())))
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return v.scalar(val, t, u, path, m)
+
+	case *types.Pointer:
+		body := v.check(deref(val, u.Elem()), u.Elem(), path, m, depth)
+		if len(body) == 0 {
+			return nil
+		}
+		return []jen.Code{jen.If(val.Clone().Op("!=").Nil()).Block(body...)}
+
+	case *types.Slice:
+		return v.items(val, nil, u.Elem(), path, m, depth)
+
+	case *types.Array:
+		return v.items(val, nil, u.Elem(), path, m, depth)
+
+	case *types.Map:
+		return v.items(val, u.Key(), u.Elem(), path, m, depth)
+
+	case *types.Struct:
+		var body []jen.Code
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			name, opts := jsonName(u.Tag(i))
+			if name == "-" || !f.Exported() {
+				continue
+			}
+			fp := path
+			switch {
+			case opts["inline"] || (f.Embedded() && name == ""):
+			case name == "":
+				fp = path.Clone().Dot("Child").Call(jen.Lit(f.Name()))
+			default:
+				fp = path.Clone().Dot("Child").Call(jen.Lit(name))
+			}
+			body = append(body, v.check(val.Clone().Dot(f.Name()), f.Type(), fp, comments.ParseMarkers(v.comments.Field(f)), depth)...)
+		}
+		return body
+	}
+	return nil
+}
+
+// items returns the validation of the supplied slice, array or map value and
+// of its items, which are validated per the supplied markers other than those
+// that apply to the value itself. The supplied key type is nil unless the
+// value is a map.
+func (v *validator) items(val *jen.Statement, key, elem types.Type, path *jen.Statement, m comments.Markers, depth int) []jen.Code {
+	var body []jen.Code
+	if key == nil {
+		if n, ok := v.integer(m, markerMinItems); ok {
+			body = append(body, jen.If(jen.Len(val.Clone()).Op("<").Lit(n)).Block(
+				v.invalid(path, jen.Len(val.Clone()), fmt.Sprintf("must have at least %d items", n)),
+			))
+		}
+		if n, ok := v.integer(m, markerMaxItems); ok {
+			body = append(body, jen.If(jen.Len(val.Clone()).Op(">").Lit(n)).Block(
+				v.invalid(path, jen.Len(val.Clone()), fmt.Sprintf("must have at most %d items", n)),
+			))
+		}
+	}
+
+	im := comments.Markers{}
+	for k, vals := range m {
+		if k != markerMinItems && k != markerMaxItems {
+			im[k] = vals
+		}
+	}
+	k, item := jen.Id(fmt.Sprintf("i%d", depth)), jen.Id(fmt.Sprintf("v%d", depth))
+	ip := path.Clone().Dot("Index").Call(k.Clone())
+	if key != nil {
+		k = jen.Id(fmt.Sprintf("k%d", depth))
+		ip = path.Clone().Dot("Key").Call(str(k.Clone(), key))
+	}
+	ib := v.check(item, elem, ip, im, depth+1)
+	if len(ib) > 0 {
+		body = append(body, jen.For(jen.List(k, item.Clone()).Op(":=").Range().Add(val.Clone())).Block(ib...))
+	}
+	return body
+}
+
+// scalar returns the validation of the supplied value of the supplied basic
+// type.
+func (v *validator) scalar(val *jen.Statement, t types.Type, b *types.Basic, path *jen.Statement, m comments.Markers) []jen.Code {
+	var body []jen.Code
+	switch {
+	case b.Info()&types.IsNumeric != 0 && b.Info()&types.IsComplex == 0:
+		integer := b.Info()&types.IsInteger != 0
+		if n, ok := v.number(m, markerMinimum, integer); ok {
+			op, msg := "<", "must be greater than or equal to %s"
+			if v.boolean(m, markerExclusiveMinimum) {
+				op, msg = "<=", "must be greater than %s"
+			}
+			body = append(body, jen.If(val.Clone().Op(op).Add(n)).Block(v.invalid(path, val, fmt.Sprintf(msg, last(m[markerMinimum])))))
+		}
+		if n, ok := v.number(m, markerMaximum, integer); ok {
+			op, msg := ">", "must be less than or equal to %s"
+			if v.boolean(m, markerExclusiveMaximum) {
+				op, msg = ">=", "must be less than %s"
+			}
+			body = append(body, jen.If(val.Clone().Op(op).Add(n)).Block(v.invalid(path, val, fmt.Sprintf(msg, last(m[markerMaximum])))))
+		}
+		if cond, ok := v.ranges(m, comments.MarkerRange, val, integer); ok {
+			body = append(body, jen.If(jen.Op("!").Parens(cond)).Block(v.invalid(path, val, "must be within range "+comments.Unquote(last(m[comments.MarkerRange])))))
+		}
+		body = append(body, v.enum(val, t, path, m, func(e string) (jen.Code, error) {
+			f, err := strconv.ParseFloat(e, 64)
+			if err != nil {
+				return nil, err
+			}
+			return lit(f, integer)
+		})...)
+
+	case b.Info()&types.IsString != 0:
+		count := jen.Qual("unicode/utf8", "RuneCountInString").Call(str(val, t))
+		if n, ok := v.integer(m, markerMinLength); ok {
+			body = append(body, jen.If(count.Clone().Op("<").Lit(n)).Block(v.invalid(path, val, fmt.Sprintf("must be at least %d characters long", n))))
+		}
+		if n, ok := v.integer(m, markerMaxLength); ok {
+			body = append(body, jen.If(count.Clone().Op(">").Lit(n)).Block(v.invalid(path, val, fmt.Sprintf("must be at most %d characters long", n))))
+		}
+		if cond, ok := v.ranges(m, comments.MarkerLength, count, true); ok {
+			body = append(body, jen.If(jen.Op("!").Parens(cond)).Block(v.invalid(path, val, "must have a length within range "+comments.Unquote(last(m[comments.MarkerLength])))))
+		}
+		if p := m[markerPattern]; len(p) > 0 {
+			pattern := comments.Unquote(last(p))
+			if _, err := regexp.Compile(pattern); err != nil {
+				v.errorf("+%s=%s: %s", markerPattern, last(p), err)
+			} else {
+				body = append(body, jen.If(jen.Op("!").Id(v.pattern(v.compile(pattern))).Dot("MatchString").Call(str(val, t))).Block(
+					v.invalid(path, val, "must match pattern "+pattern),
+				))
+			}
+		}
+		body = append(body, v.enum(val, t, path, m, func(e string) (jen.Code, error) { return jen.Lit(e), nil })...)
+	}
+	return body
+}
+
+// enum returns the validation of the supplied value against the values of
+// the enum marker, if any, which are written by the supplied function.
+func (v *validator) enum(val *jen.Statement, t types.Type, path *jen.Statement, m comments.Markers, value func(e string) (jen.Code, error)) []jen.Code {
+	e := m[markerEnum]
+	if len(e) == 0 {
+		return nil
+	}
+	l := comments.List(last(e))
+	cases := make([]jen.Code, 0, len(l))
+	supported := make([]jen.Code, 0, len(l))
+	for _, s := range l {
+		c, err := value(s)
+		if err != nil {
+			v.errorf("+%s=%s: %s", markerEnum, last(e), err)
+			return nil
+		}
+		cases = append(cases, c)
+		supported = append(supported, jen.Lit(s))
+	}
+	return []jen.Code{jen.Switch(val.Clone()).Block(
+		jen.Case(cases...),
+		jen.Default().Block(
+			jen.Id("errs").Op("=").Append(jen.Id("errs"), jen.Qual(FieldPath, "NotSupported").Call(path.Clone(), val.Clone(), jen.Index().String().Values(supported...))),
+		),
+	)}
+}
+
+// ranges returns a condition that is true if the supplied value is within the
+// ranges of the supplied marker, e.g. 1..10|20..max, if it is present.
+func (v *validator) ranges(m comments.Markers, key string, val *jen.Statement, integer bool) (jen.Code, bool) {
+	r := m[key]
+	if len(r) == 0 {
+		return nil, false
+	}
+	var conds []jen.Code
+	for _, part := range strings.Split(comments.Unquote(last(r)), "|") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "..", 2)
+		if len(bounds) == 1 {
+			bounds = append(bounds, bounds[0])
+		}
+		var cond []jen.Code
+		for i, op := range []string{">=", "<="} {
+			b := strings.TrimSpace(bounds[i])
+			if b == "min" || b == "max" {
+				continue
+			}
+			f, err := strconv.ParseFloat(b, 64)
+			if err != nil {
+				v.errorf("+%s=%s: %q is not a number", key, last(r), b)
+				return nil, false
+			}
+			n, err := lit(f, integer)
+			if err != nil {
+				v.errorf("+%s=%s: %s", key, last(r), err)
+				return nil, false
+			}
+			cond = append(cond, val.Clone().Op(op).Add(n))
+		}
+		if len(cond) == 0 {
+			// The range min..max allows any value.
+			return nil, false
+		}
+		c := jen.Add(cond[0])
+		for _, o := range cond[1:] {
+			c = c.Op("&&").Add(o)
+		}
+		conds = append(conds, c)
+	}
+	c := jen.Add(conds[0])
+	for _, o := range conds[1:] {
+		c = c.Op("||").Add(o)
+	}
+	return c, true
+}
+
+// number returns the value of the supplied numeric marker, if it is present.
+func (v *validator) number(m comments.Markers, key string, integer bool) (jen.Code, bool) {
+	vals := m[key]
+	if len(vals) == 0 {
+		return nil, false
+	}
+	f, err := strconv.ParseFloat(comments.Unquote(last(vals)), 64)
+	if err != nil {
+		v.errorf("+%s=%s: not a number", key, last(vals))
+		return nil, false
+	}
+	n, err := lit(f, integer)
+	if err != nil {
+		v.errorf("+%s=%s: %s", key, last(vals), err)
+		return nil, false
+	}
+	return n, true
+}
+
+// integer returns the value of the supplied integer marker, if it is present.
+func (v *validator) integer(m comments.Markers, key string) (int, bool) {
+	vals := m[key]
+	if len(vals) == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(comments.Unquote(last(vals)))
+	if err != nil {
+		v.errorf("+%s=%s: not an integer", key, last(vals))
+		return 0, false
+	}
+	return n, true
+}
+
+// boolean returns the value of the supplied boolean marker, which is true if
+// it is present without a value.
+func (v *validator) boolean(m comments.Markers, key string) bool {
+	vals := m[key]
+	if len(vals) == 0 {
+		return false
+	}
+	if last(vals) == "" {
+		return true
+	}
+	b, err := strconv.ParseBool(last(vals))
+	if err != nil {
+		v.errorf("+%s=%s: not a boolean", key, last(vals))
+	}
+	return b
+}
+
+func (v *validator) invalid(path, val jen.Code, msg string) jen.Code {
+	return jen.Id("errs").Op("=").Append(jen.Id("errs"), jen.Qual(FieldPath, "Invalid").Call(path, val, jen.Lit(msg)))
+}
+
+func (v *validator) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, v.object.Name()+": "+fmt.Sprintf(format, args...))
+}
+
+// lit returns the supplied number as an untyped constant, which must be an
+// integer if the supplied integer is true.
+func lit(f float64, integer bool) (jen.Code, error) {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return jen.Lit(int(f)), nil
+	}
+	if integer {
+		return nil, errors.Errorf("%v is not an integer", f)
+	}
+	return jen.Lit(f), nil
+}
+
+// merge returns the supplied markers, and those of the supplied defaults that
+// they do not specify.
+func merge(m, defaults comments.Markers) comments.Markers {
+	out := comments.Markers{}
+	for k, vals := range defaults {
+		out[k] = vals
+	}
+	for k, vals := range m {
+		out[k] = vals
+	}
+	return out
+}
+
+func jsonName(tag string) (string, map[string]bool) {
+	parts := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+func last(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[len(s)-1]
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rbac generates Kubernetes RBAC ClusterRoles.
+package rbac
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+// MarkerRBAC declares an additional rule, for example
+// +ndd:rbac:groups=core,resources=secrets,verbs=get;list;watch. Lists are
+// separated by semicolons. The core API group may be written as core or "".
+const MarkerRBAC = "ndd:rbac"
+
+// A ClusterRole of the rbac.authorization.k8s.io/v1 API.
+type ClusterRole struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   Metadata `yaml:"metadata"`
+	Rules      []Rule   `yaml:"rules"`
+}
+
+// Metadata of a ClusterRole.
+type Metadata struct {
+	Name string `yaml:"name"`
+}
+
+// A Rule of a ClusterRole.
+type Rule struct {
+	APIGroups     []string `yaml:"apiGroups"`
+	Resources     []string `yaml:"resources"`
+	ResourceNames []string `yaml:"resourceNames,omitempty"`
+	Verbs         []string `yaml:"verbs"`
+}
+
+// NewClusterRole returns a ClusterRole with the supplied name and rules. Rules
+// that differ only in their resources are merged, and rules are sorted.
+func NewClusterRole(name string, rules ...Rule) *ClusterRole {
+	return &ClusterRole{
+		APIVersion: "rbac.authorization.k8s.io/v1",
+		Kind:       "ClusterRole",
+		Metadata:   Metadata{Name: name},
+		Rules:      normalize(rules),
+	}
+}
+
+// Marshal the supplied ClusterRole to YAML, carrying generate.HeaderGenerated.
+func Marshal(c *ClusterRole) ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal ClusterRole")
+	}
+	b := &bytes.Buffer{}
+	b.WriteString("# " + generate.HeaderGenerated + "\n---\n")
+	b.Write(data)
+	return b.Bytes(), nil
+}
+
+// RulesOf returns the rules declared by the supplied markers using the
+// MarkerRBAC marker.
+func RulesOf(m comments.Markers) ([]Rule, error) {
+	var rules []Rule
+	for _, args := range m.Args(MarkerRBAC) {
+		r := Rule{
+			APIGroups:     split(args["groups"]),
+			Resources:     split(args["resources"]),
+			ResourceNames: split(args["resourceNames"]),
+			Verbs:         split(args["verbs"]),
+		}
+		if _, ok := args["groups"]; !ok || len(r.Resources) == 0 || len(r.Verbs) == 0 {
+			return nil, errors.Errorf("+%s marker requires groups, resources and verbs", MarkerRBAC)
+		}
+		if len(r.APIGroups) == 0 {
+			r.APIGroups = []string{""}
+		}
+		for i := range r.APIGroups {
+			if r.APIGroups[i] == "core" {
+				r.APIGroups[i] = ""
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// split splits a semicolon separated marker argument. Unlike comments.List
+// it preserves empty elements, which denote the core API group.
+func split(v string) []string {
+	if v == "" {
+		return nil
+	}
+	l := strings.Split(v, ";")
+	for i := range l {
+		l[i] = comments.Unquote(strings.TrimSpace(l[i]))
+	}
+	return l
+}
+
+func normalize(rules []Rule) []Rule {
+	merged := map[string]*Rule{}
+	keys := []string{}
+	for _, r := range rules {
+		k := strings.Join([]string{key(r.APIGroups), key(r.ResourceNames), key(r.Verbs)}, "|")
+		m, ok := merged[k]
+		if !ok {
+			m = &Rule{APIGroups: unique(r.APIGroups), ResourceNames: unique(r.ResourceNames), Verbs: unique(r.Verbs)}
+			merged[k] = m
+			keys = append(keys, k)
+		}
+		m.Resources = unique(append(m.Resources, r.Resources...))
+	}
+	sort.Strings(keys)
+	out := make([]Rule, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, *merged[k])
+	}
+	return out
+}
+
+func key(s []string) string {
+	return strings.Join(unique(s), ";")
+}
+
+// unique returns the sorted, unique elements of the supplied slice.
+func unique(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(s))
+	for _, e := range s {
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
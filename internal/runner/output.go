@@ -0,0 +1,134 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/cache"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+const (
+	errPrintGeneratedFile = "cannot print generated file"
+	errOutsideRoot        = "generated file is outside of the module root"
+	errCreateOutputDir    = "cannot create output directory"
+)
+
+// printFiles returns a generate.FileWriter that prints every generated file,
+// preceded by a banner naming its path, to the supplied writer.
+func printFiles(out io.Writer) generate.FileWriter {
+	return func(file string, data []byte) error {
+		if _, err := fmt.Fprintf(out, "// ---- %s ----\n%s\n", file, data); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%s : %s", errPrintGeneratedFile, file))
+		}
+		return nil
+	}
+}
+
+// printRemovedFiles returns a generate.FileRemover that prints a banner
+// naming every previously generated file that would be removed.
+func printRemovedFiles(out io.Writer) generate.FileRemover {
+	return func(file string) error {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return nil
+		}
+		if generated, err := generate.IsGeneratedFile(file); err != nil || !generated {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "// ---- %s (removed) ----\n", file); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%s : %s", errPrintGeneratedFile, file))
+		}
+		return nil
+	}
+}
+
+// writeFilesUnder returns a generate.FileWriter that writes every generated
+// file to the supplied directory rather than next to its package. The path of
+// the file relative to the supplied root is preserved.
+func writeFilesUnder(dir, root string) generate.FileWriter {
+	return func(file string, data []byte) error {
+		path, err := mirror(dir, root, file)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil { // nolint:gosec
+			return errors.Wrap(err, fmt.Sprintf("%s : %s", errCreateOutputDir, filepath.Dir(path)))
+		}
+		return generate.WriteFile(path, data)
+	}
+}
+
+// removeFilesUnder returns a generate.FileRemover that removes previously
+// generated files from the supplied directory, mirroring writeFilesUnder.
+func removeFilesUnder(dir, root string) generate.FileRemover {
+	return func(file string) error {
+		path, err := mirror(dir, root, file)
+		if err != nil {
+			return err
+		}
+		return generate.RemoveFile(path)
+	}
+}
+
+// mirror returns the path under dir of the supplied file, relative to root.
+func mirror(dir, root, file string) (string, error) {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", errors.Errorf("%s : %s", errOutsideRoot, file)
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// recordFiles returns a generate.FileWriter and generate.FileRemover that
+// persist generated files using generate.WriteFile and generate.RemoveFile,
+// recording a cache.Hash of each written file in the supplied map. Removed
+// files are recorded with an empty hash.
+func recordFiles(outputs map[string]string) (generate.FileWriter, generate.FileRemover) {
+	w := func(file string, data []byte) error {
+		if err := generate.WriteFile(file, data); err != nil {
+			return err
+		}
+		outputs[file] = cache.Hash(data)
+		return nil
+	}
+	r := func(file string) error {
+		if err := generate.RemoveFile(file); err != nil {
+			return err
+		}
+		outputs[file] = ""
+		return nil
+	}
+	return w, r
+}
+
+// rootOf returns the directory against which the generated files of the
+// supplied package are mirrored; its module root if known, or the supplied
+// working directory.
+func rootOf(p *packages.Package, wd string) string {
+	if p.Module != nil && p.Module.Dir != "" {
+		return p.Module.Dir
+	}
+	return wd
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+)
+
+const (
+	// LoadMode used to load all packages.
+	LoadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedModule
+
+	// DisableMarker used to disable generation of managed resource methods for
+	// a type that otherwise appears to be a managed resource that is missing a
+	// subset of its methods.
+	DisableMarker = "ndd:generate:methods"
+)
+
+// A Package is a loaded package along with the state that is shared by all of
+// the generators that run for it. It is computed once per package, and is not
+// safe for concurrent use.
+type Package struct {
+	*packages.Package
+
+	// Comments of the package.
+	Comments comments.Comments
+
+	// Enabled matches the objects for which method generation has not been
+	// disabled using the DisableMarker.
+	Enabled match.Object
+
+	managed              match.Object
+	managedList          match.Object
+	networkNode          match.Object
+	networkNodeUsage     match.Object
+	networkNodeUsageList match.Object
+	managedSpecs         match.Object
+
+	tags   string
+	wd     string
+	loader *loader
+}
+
+// NewPackage computes the shared state of the supplied package, which was
+// loaded in the current working directory without build tags.
+func NewPackage(p *packages.Package) *Package {
+	wd, _ := os.Getwd()
+	return newPackage(Variant{Package: p}, wd, &loader{})
+}
+
+// newPackage computes the shared state of the supplied package variant, which
+// was loaded in the supplied working directory. Packages it imports are
+// loaded using the supplied loader.
+func newPackage(v Variant, wd string, l *loader) *Package {
+	c := comments.In(v.Package)
+	enabled := match.Memoize(match.DoesNotHaveMarker(c, DisableMarker, "false"))
+	return &Package{
+		Package:              v.Package,
+		Comments:             c,
+		Enabled:              enabled,
+		managed:              match.Memoize(match.AllOf(match.Managed(), enabled)),
+		managedList:          match.Memoize(match.AllOf(match.ManagedList(), enabled)),
+		networkNode:          match.Memoize(match.AllOf(match.NetworkNode(), enabled)),
+		networkNodeUsage:     match.Memoize(match.AllOf(match.NetworkNodeUsage(), enabled)),
+		networkNodeUsageList: match.Memoize(match.AllOf(match.NetworkNodeUsageList(), enabled)),
+		tags:                 v.Tags,
+		wd:                   wd,
+		loader:               l,
+	}
+}
+
+// Managed returns an Object matcher that returns true if the supplied object
+// is a managed resource for which method generation is enabled. Its results
+// are cached, so that the generators of the package share them.
+func (p *Package) Managed() match.Object { return p.managed }
+
+// ManagedList returns an Object matcher that returns true if the supplied
+// object is a managed resource list for which method generation is enabled.
+func (p *Package) ManagedList() match.Object { return p.managedList }
+
+// NetworkNode returns an Object matcher that returns true if the supplied
+// object is a NetworkNode for which method generation is enabled.
+func (p *Package) NetworkNode() match.Object { return p.networkNode }
+
+// NetworkNodeUsage returns an Object matcher that returns true if the supplied
+// object is a NetworkNodeUsage for which method generation is enabled.
+func (p *Package) NetworkNodeUsage() match.Object { return p.networkNodeUsage }
+
+// NetworkNodeUsageList returns an Object matcher that returns true if the
+// supplied object is a NetworkNodeUsage list for which method generation is
+// enabled.
+func (p *Package) NetworkNodeUsageList() match.Object { return p.networkNodeUsageList }
+
+// ManagedSpecs returns an Object matcher that returns true if the supplied
+// object is the spec type, declared by the package, of one of its managed
+// resources for which method generation is enabled. The specs are found the
+// first time ManagedSpecs is called.
+func (p *Package) ManagedSpecs() match.Object {
+	if p.managedSpecs != nil {
+		return p.managedSpecs
+	}
+	specs := map[types.Object]bool{}
+	for _, n := range p.Types.Scope().Names() {
+		o := p.Types.Scope().Lookup(n)
+		if !p.managed(o) {
+			continue
+		}
+		v, _, _ := types.LookupFieldOrMethod(o.Type(), true, nil, fields.NameSpec)
+		f, ok := v.(*types.Var)
+		if !ok || !f.IsField() {
+			continue
+		}
+		if s, ok := f.Type().(*types.Named); ok && s.Obj().Pkg() == p.Types {
+			specs[s.Obj()] = true
+		}
+	}
+	p.managedSpecs = func(o types.Object) bool { return specs[o] }
+	return p.managedSpecs
+}
+
+// Root returns the directory to which paths that generators write outside of
+// the package are relative; the module root of the package if known, or the
+// directory in which it was loaded.
+func (p *Package) Root() string {
+	return rootOf(p.Package, p.wd)
+}
+
+// Import returns the types of the package with the supplied import path. They
+// are found among the dependencies of the package if possible, and loaded
+// otherwise, in the directory and environment and with the build tags that
+// the package was loaded with.
+func (p *Package) Import(path string) (*types.Package, error) {
+	if ip := findImport(p.Package, path, map[string]bool{}); ip != nil {
+		return ip.Types, nil
+	}
+	return p.loader.Load(path, p.tags)
+}
+
+func findImport(p *packages.Package, path string, seen map[string]bool) *packages.Package {
+	if seen[p.PkgPath] {
+		return nil
+	}
+	seen[p.PkgPath] = true
+	if ip, ok := p.Imports[path]; ok {
+		return ip
+	}
+	for _, ip := range p.Imports {
+		if found := findImport(ip, path, seen); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// A loader loads the types of packages that were not loaded by a run, in the
+// directory and environment of the run. Each package is loaded once per set
+// of build tags. A loader is safe for concurrent use.
+type loader struct {
+	dir string
+	env []string
+
+	mu     sync.Mutex
+	loaded map[string]*loaded
+}
+
+// A loaded package, or the error encountered loading it.
+type loaded struct {
+	once sync.Once
+	p    *types.Package
+	err  error
+}
+
+// Load returns the types of the package with the supplied import path, as
+// loaded with the supplied comma separated build tags.
+func (l *loader) Load(path, tags string) (*types.Package, error) {
+	l.mu.Lock()
+	if l.loaded == nil {
+		l.loaded = map[string]*loaded{}
+	}
+	key := tags + ":" + path
+	r, ok := l.loaded[key]
+	if !ok {
+		r = &loaded{}
+		l.loaded[key] = r
+	}
+	l.mu.Unlock()
+
+	r.once.Do(func() {
+		cfg := &packages.Config{Mode: LoadMode, Dir: l.dir}
+		if tags != "" {
+			cfg.BuildFlags = []string{"-tags=" + tags}
+		}
+		if len(l.env) > 0 {
+			cfg.Env = append(os.Environ(), l.env...)
+		}
+		pkgs, err := packages.Load(cfg, path)
+		if err != nil {
+			r.err = errors.Wrap(err, fmt.Sprintf("%s : %s", errLoadPackages, path))
+			return
+		}
+		if len(pkgs) != 1 || pkgs[0].Types == nil || len(pkgs[0].Errors) > 0 {
+			r.err = errors.Errorf("%s : %s", errLoadTypes, path)
+			return
+		}
+		r.p = pkgs[0].Types
+	})
+	return r.p, r.err
+}
+
+// workingDir returns the directory in which packages are loaded, or the
+// working directory.
+func (rc Config) workingDir() (string, error) {
+	if rc.Dir != "" {
+		return rc.Dir, nil
+	}
+	wd, err := os.Getwd()
+	return wd, errors.Wrap(err, "cannot determine working directory")
+}
+
+// A Variant of a package, as loaded using a particular set of build tags.
+type Variant struct {
+	*packages.Package
+
+	// Tags used to load the package, if any.
+	Tags string
+}
+
+// LoadPackages loads the packages matching the supplied patterns once for each
+// configured build tag set, using the configured directory and environment.
+// Packages are returned grouped by import path, in the order they were first
+// loaded. A group contains one Variant per tag set the package was loaded with.
+func (rc Config) LoadPackages(patterns []string) ([][]Variant, error) {
+	tagSets := rc.Tags
+	if len(tagSets) == 0 {
+		tagSets = []string{""}
+	}
+
+	groups := [][]Variant{}
+	index := map[string]int{}
+	for _, t := range tagSets {
+		cfg := &packages.Config{Mode: LoadMode, Dir: rc.Dir}
+		if t != "" {
+			cfg.BuildFlags = []string{"-tags=" + t}
+		}
+		if len(rc.Env) > 0 {
+			cfg.Env = append(os.Environ(), rc.Env...)
+		}
+		pkgs, err := packages.Load(cfg, patterns...)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%s : %s", errLoadPackages, patterns))
+		}
+		for _, p := range pkgs {
+			i, ok := index[p.PkgPath]
+			if !ok {
+				i = len(groups)
+				index[p.PkgPath] = i
+				groups = append(groups, nil)
+			}
+			groups[i] = append(groups[i], Variant{Package: p, Tags: t})
+		}
+	}
+	return groups, nil
+}
+
+// forEach calls fn with each index in [0, n), using at most the supplied
+// number of concurrent workers. It returns once fn has returned for every
+// index.
+func forEach(n, jobs int, fn func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	work := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// A fileSet collects generated files in memory, for example so that the files
+// generated for several variants of a package can be compared before they are
+// written. Files that would be removed map to nil data.
+type fileSet map[string][]byte
+
+// Write is a generate.FileWriter that adds the supplied file to the set.
+func (fs fileSet) Write(file string, data []byte) error {
+	if data == nil {
+		data = []byte{}
+	}
+	fs[file] = data
+	return nil
+}
+
+// Remove is a generate.FileRemover that records that the supplied file would
+// be removed.
+func (fs fileSet) Remove(file string) error {
+	fs[file] = nil
+	return nil
+}
+
+// Flush writes or removes every file in the set, in order of their names. It
+// returns the first error encountered.
+func (fs fileSet) Flush(w generate.FileWriter, r generate.FileRemover) error {
+	files := make([]string, 0, len(fs))
+	for file := range fs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		var err error
+		if fs[file] == nil {
+			err = r(file)
+		} else {
+			err = w(file, fs[file])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFileSets merges the file sets generated for several variants of a
+// package. Files that not all variants agree on are omitted from the merged
+// set, and an error is returned for each of them.
+func mergeFileSets(vs []Variant, sets []fileSet) (fileSet, []error) {
+	files := map[string]bool{}
+	for _, fs := range sets {
+		for file := range fs {
+			files[file] = true
+		}
+	}
+
+	merged := fileSet{}
+	var errs []error
+	for file := range files {
+		if conflicts(file, sets) {
+			errs = append(errs, errors.Errorf("%s : %s differs between build tags %s", errConflictingVariants, file, tagsOf(vs)))
+			continue
+		}
+		merged[file] = sets[0][file]
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return merged, errs
+}
+
+// conflicts returns true if the supplied file sets disagree on the supplied
+// file.
+func conflicts(file string, sets []fileSet) bool {
+	data, ok := sets[0][file]
+	for _, fs := range sets[1:] {
+		d, has := fs[file]
+		if has != ok || (d == nil) != (data == nil) || !bytes.Equal(d, data) {
+			return true
+		}
+	}
+	return false
+}
+
+func tagsOf(vs []Variant) []string {
+	t := make([]string, 0, len(vs))
+	for _, v := range vs {
+		t = append(t, fmt.Sprintf("%q", v.Tags))
+	}
+	return t
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+const (
+	errGenerationFailed = "generation failed"
+
+	// scopeLoad is the scope of errors encountered while loading a package.
+	scopeLoad = "load"
+)
+
+// A failure is an error encountered within a scope (e.g. a generator) while
+// processing a package.
+type failure struct {
+	scope string
+	err   error
+}
+
+// A report collects the errors encountered while processing packages, so
+// that processing can continue with the remaining packages. It is safe for
+// concurrent use.
+type report struct {
+	mu       sync.Mutex
+	packages int
+	failures map[string][]failure
+}
+
+func newReport() *report {
+	return &report{failures: map[string][]failure{}}
+}
+
+// Processed records that a package was processed, regardless of whether it
+// failed.
+func (r *report) Processed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packages++
+}
+
+// LoadErrors records every load error of the supplied package, except type
+// errors if they are ok. It returns true if it recorded any.
+func (r *report) LoadErrors(p *packages.Package, typeErrorsOK bool) bool {
+	failed := false
+	for _, err := range p.Errors {
+		if tolerated(err, typeErrorsOK) {
+			continue
+		}
+		r.Add(p.PkgPath, scopeLoad, loadError(err))
+		failed = true
+	}
+	return failed
+}
+
+// Add records an error encountered within the supplied scope while processing
+// the supplied package.
+func (r *report) Add(pkgPath, scope string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures[pkgPath] = append(r.failures[pkgPath], failure{scope: scope, err: err})
+}
+
+// Print a summary of all recorded errors, grouped by package, to the supplied
+// writer.
+func (r *report) Print(out io.Writer) {
+	if len(r.failures) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "%s for %d of %d package(s):\n", errGenerationFailed, len(r.failures), r.packages)
+	for _, pkgPath := range r.failed() {
+		fmt.Fprintf(out, "%s\n", pkgPath)
+		for _, f := range r.failures[pkgPath] {
+			fmt.Fprintf(out, "  %s: %s\n", f.scope, f.err)
+		}
+	}
+}
+
+// Err returns an error if any errors were recorded.
+func (r *report) Err() error {
+	if len(r.failures) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s for %d of %d package(s)", errGenerationFailed, len(r.failures), r.packages)
+}
+
+func (r *report) failed() []string {
+	pkgPaths := make([]string, 0, len(r.failures))
+	for pkgPath := range r.failures {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+	return pkgPaths
+}
+
+// tolerated returns true if the supplied load error does not prevent the
+// package from being generated. Type errors are tolerated if the generators
+// allow them, or if they occur in a file generated by ndd-gen, since
+// regenerating the package may be what fixes them.
+func tolerated(e packages.Error, typeErrorsOK bool) bool {
+	if e.Kind != packages.TypeError {
+		return false
+	}
+	if typeErrorsOK {
+		return true
+	}
+	// Positions are of the form file:line:col.
+	file := e.Pos
+	for n := 0; n < 2; n++ {
+		if i := strings.LastIndex(file, ":"); i > 0 {
+			file = file[:i]
+		}
+	}
+	data, err := ioutil.ReadFile(file) // nolint:gosec
+	return err == nil && generate.IsGenerated(data)
+}
+
+// loadError returns an error that is prefixed with the file:line position of
+// the supplied packages.Error, if it has one.
+func loadError(e packages.Error) error {
+	if e.Pos == "" || e.Pos == "-" {
+		return errors.New(e.Msg)
+	}
+	return errors.Errorf("%s: %s", e.Pos, e.Msg)
+}
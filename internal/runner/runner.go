@@ -0,0 +1,482 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runner runs generators for packages; it loads them, applies the
+// ndd-gen config file, skips those that are unchanged since they were last
+// generated, and writes, verifies or prints what the generators produce.
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/cache"
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+const (
+	errLoadPackages         = "cannot load packages"
+	errLoadTypes            = "cannot load types of package"
+	errReadheaderFile       = "cannot read header file"
+	errLoadConfig           = "cannot load config"
+	errNoPaths              = "no packages to generate, use --paths or set paths in the config file"
+	errNoGenerators         = "no generators to run"
+	errNoGeneratorName      = "generator has no name"
+	errNoGenerateFunc       = "generator has no Generate function"
+	errExclusiveOutputFlags = "--verify, --dry-run and --output-dir are mutually exclusive"
+	errLoadCache            = "cannot load cache"
+	errConflictingVariants  = "conflicting generated file"
+
+	// scopeCache is the scope of errors encountered while using the cache.
+	scopeCache = "cache"
+
+	// scopeTags is the scope of errors encountered while reconciling the
+	// variants of a package loaded using different build tags.
+	scopeTags = "tags"
+
+	// scopeWrite is the scope of errors encountered while writing generated
+	// files.
+	scopeWrite = "write"
+)
+
+// A GeneratorFunc generates code for the supplied package, per the supplied
+// configuration and imports. Generated files carry the supplied header, and
+// must be written using the supplied WriteOptions.
+type GeneratorFunc func(g config.Generator, i config.Imports, header string, p *Package, wo ...generate.WriteOption) error
+
+// A Config configures a run of generators.
+type Config struct {
+	// Name of the run, which keys its results in the cache, e.g. the name of
+	// the command that runs it. Defaults to the names of its generators.
+	Name string
+
+	// Version of the generators. Results cached by another version are not
+	// reused.
+	Version string
+
+	// ConfigFile is the ndd-gen config file. Defaults to config.Filename at
+	// the root of the module, if it exists.
+	ConfigFile string
+
+	// Paths of the packages to load, e.g. ./apis/... Overrides the paths of
+	// the config file.
+	Paths []string
+
+	// Tags are comma separated lists of build tags to load packages with.
+	// Packages are loaded once per tag set.
+	Tags []string
+
+	// Dir in which to load packages, and from which to discover the module
+	// root. Defaults to the working directory.
+	Dir string
+
+	// Env are environment variables, e.g. GOOS=linux, to load packages with.
+	Env []string
+
+	// HeaderFile whose contents are added to the top of all generated files.
+	// Overrides the header file of the config file.
+	HeaderFile string
+
+	// Verify compares generated files against the files on disk without
+	// writing them, and fails if they differ.
+	Verify bool
+
+	// DryRun prints generated files to Stdout instead of writing them.
+	DryRun bool
+
+	// OutputDir to which generated files are written, mirroring the package
+	// layout, instead of next to their package.
+	OutputDir string
+
+	// Jobs is the number of packages to generate concurrently. Defaults to
+	// the number of CPUs.
+	Jobs int
+
+	// CacheFile in which to cache generation results. Defaults to
+	// cache.Filename at the root of the module.
+	CacheFile string
+
+	// Force generates all packages, even those that are unchanged since they
+	// were last generated.
+	Force bool
+
+	// Stdout is where generated files, diffs and warnings are printed.
+	// Defaults to os.Stdout.
+	Stdout io.Writer
+
+	// Stderr is where a summary of errors is printed. Defaults to os.Stderr.
+	Stderr io.Writer
+
+	// Generators to run, in order.
+	Generators []Generator
+}
+
+// A Generator is a GeneratorFunc and the name of the config.Generator that
+// configures it.
+type Generator struct {
+	// Name of the config.Generator that configures the generator.
+	Name string
+
+	// Defaults of the config.Generator, which the config file overrides.
+	Defaults config.Generator
+
+	// Generate code for a package.
+	Generate GeneratorFunc
+
+	// TypeErrorsOK allows the generator to run for packages that do not type
+	// check, for example because they lack the methods it generates.
+	TypeErrorsOK bool
+
+	// Inputs are the names of the config.Generators whose generated files are
+	// inputs of the generator, for example because what it generates depends
+	// on whether they generated a method. Generated files are otherwise not
+	// inputs of a run.
+	Inputs []string
+}
+
+// Run the generators of the supplied Config, in order, for every configured
+// package and write, verify or print what they generate.
+func Run(rc Config) error {
+	c, err := rc.LoadConfig()
+	if err != nil {
+		return err
+	}
+	return RunWith(rc, c)
+}
+
+// RunWith runs the generators of the supplied Config as Run does, but per the
+// supplied config rather than the one loaded by rc.LoadConfig, for example so
+// that the caller may override it.
+func RunWith(rc Config, c *config.Config) error {
+	if err := rc.validate(); err != nil {
+		return err
+	}
+	if err := CheckPaths(c); err != nil {
+		return err
+	}
+	if rc.Name == "" {
+		names := make([]string, 0, len(rc.Generators))
+		for _, g := range rc.Generators {
+			names = append(names, g.Name)
+		}
+		rc.Name = strings.Join(names, ",")
+	}
+	if rc.Stdout == nil {
+		rc.Stdout = os.Stdout
+	}
+	if rc.Stderr == nil {
+		rc.Stderr = os.Stderr
+	}
+
+	wd, err := rc.workingDir()
+	if err != nil {
+		return err
+	}
+	groups, err := rc.LoadPackages(c.Paths)
+	if err != nil {
+		return err
+	}
+	// Packages that generators import but that were not loaded, e.g. the
+	// resource package, are loaded at most once per run.
+	l := &loader{dir: rc.Dir, env: rc.Env}
+
+	header := ""
+	if c.HeaderFile != "" {
+		h, err := ioutil.ReadFile(c.HeaderFile)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%s : %s", errReadheaderFile, c.HeaderFile))
+		}
+		header = string(h)
+	}
+
+	var v *verifier
+	if rc.Verify {
+		v = newVerifier(rc.Stdout)
+	}
+
+	// The cache is only used when generated files are written in place.
+	var ch *cache.Cache
+	if !rc.Verify && !rc.DryRun && rc.OutputDir == "" {
+		if ch, err = rc.loadCache(); err != nil {
+			return errors.Wrap(err, errLoadCache)
+		}
+	}
+	var skipped int32
+
+	// Packages are generated concurrently. Anything they print is buffered
+	// and flushed in the order the packages were loaded so that output is
+	// deterministic.
+	typeErrorsOK := true
+	inputs := map[string]bool{}
+	for _, g := range rc.Generators {
+		typeErrorsOK = typeErrorsOK && g.TypeErrorsOK
+		for _, name := range g.Inputs {
+			inputs[name] = true
+		}
+	}
+	jobs := rc.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	r := newReport()
+	out := make([]*bytes.Buffer, len(groups))
+	verifiers := make([]*verifier, len(groups))
+	forEach(len(groups), jobs, func(i int) {
+		out[i] = &bytes.Buffer{}
+		variants := groups[i]
+		pkgPath := variants[0].PkgPath
+		key := rc.Name + ":" + pkgPath
+		r.Processed()
+		loadFailed := false
+		for _, pv := range variants {
+			warnTypeErrors(out[i], pv.Package, typeErrorsOK)
+			loadFailed = r.LoadErrors(pv.Package, typeErrorsOK) || loadFailed
+		}
+		if loadFailed {
+			return
+		}
+		pc := c.For(pkgPath)
+
+		var hash string
+		if ch != nil {
+			var err error
+			if hash, err = cacheInputs(pc, rc.Version, header, variants, inputs); err != nil {
+				r.Add(pkgPath, scopeCache, err)
+				return
+			}
+			if !rc.Force && ch.Fresh(key, hash) {
+				atomic.AddInt32(&skipped, 1)
+				return
+			}
+		}
+
+		// Every variant of the package is generated in memory. Only the
+		// files that all variants agree on are written.
+		failed := false
+		sets := make([]fileSet, 0, len(variants))
+		for _, pv := range variants {
+			fs := fileSet{}
+			p := newPackage(pv, wd, l)
+			for _, gen := range rc.Generators {
+				g := pc.Generator(gen.Name)
+				if !g.IsEnabled() {
+					continue
+				}
+				if err := gen.Generate(g, pc.Imports, header, p, generate.WithFileWriter(fs.Write), generate.WithFileRemover(fs.Remove)); err != nil {
+					r.Add(pkgPath, gen.Name, err)
+					failed = true
+				}
+			}
+			sets = append(sets, fs)
+		}
+		files, conflicts := mergeFileSets(variants, sets)
+		for _, err := range conflicts {
+			r.Add(pkgPath, scopeTags, err)
+			failed = true
+		}
+
+		var w generate.FileWriter = generate.WriteFile
+		var rm generate.FileRemover = generate.RemoveFile
+		outputs := map[string]string{}
+		switch {
+		case v != nil:
+			verifiers[i] = newVerifier(out[i])
+			w, rm = verifiers[i].Write, verifiers[i].Remove
+		case rc.DryRun:
+			w, rm = printFiles(out[i]), printRemovedFiles(out[i])
+		case rc.OutputDir != "":
+			root := rootOf(variants[0].Package, wd)
+			w, rm = writeFilesUnder(rc.OutputDir, root), removeFilesUnder(rc.OutputDir, root)
+		case ch != nil:
+			w, rm = recordFiles(outputs)
+		}
+		if err := files.Flush(w, rm); err != nil {
+			r.Add(pkgPath, scopeWrite, err)
+			failed = true
+		}
+		if ch != nil && !failed {
+			ch.Put(key, hash, outputs)
+		}
+	})
+	for i := range groups {
+		rc.Stdout.Write(out[i].Bytes()) // nolint:errcheck
+		if v != nil && verifiers[i] != nil {
+			v.Merge(verifiers[i])
+		}
+	}
+
+	if ch != nil {
+		if err := ch.Save(); err != nil {
+			return err
+		}
+		if skipped > 0 {
+			fmt.Fprintf(rc.Stdout, "skipped %d unchanged package(s)\n", skipped)
+		}
+	}
+
+	r.Print(rc.Stderr)
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if v != nil {
+		return v.Err()
+	}
+	return nil
+}
+
+// validate returns an error if the Config cannot be run.
+func (rc Config) validate() error {
+	if exclusive(rc.Verify, rc.DryRun, rc.OutputDir != "") {
+		return errors.New(errExclusiveOutputFlags)
+	}
+	if len(rc.Generators) == 0 {
+		return errors.New(errNoGenerators)
+	}
+	for i, g := range rc.Generators {
+		if g.Name == "" {
+			return errors.Errorf("%s : generator %d", errNoGeneratorName, i)
+		}
+		if g.Generate == nil {
+			return errors.Errorf("%s : %s", errNoGenerateFunc, g.Name)
+		}
+	}
+	return nil
+}
+
+// CheckPaths returns an error if the supplied config has no packages to
+// generate.
+func CheckPaths(c *config.Config) error {
+	if len(c.Paths) == 0 {
+		return errors.New(errNoPaths)
+	}
+	return nil
+}
+
+// LoadConfig loads the config file of the Config, or the one discovered at the
+// module root, applies the defaults of its generators, and overrides its
+// paths and header file with those of the Config, if any.
+func (rc Config) LoadConfig() (*config.Config, error) {
+	path := rc.ConfigFile
+	if path == "" {
+		wd, err := rc.workingDir()
+		if err != nil {
+			return nil, errors.Wrap(err, errLoadConfig)
+		}
+		if path, err = config.Discover(wd); err != nil {
+			return nil, errors.Wrap(err, errLoadConfig)
+		}
+	}
+
+	c := config.Default()
+	if path != "" {
+		var err error
+		if c, err = config.Load(path); err != nil {
+			return nil, errors.Wrap(err, errLoadConfig)
+		}
+	}
+
+	for _, g := range rc.Generators {
+		c.SetDefaults(g.Name, g.Defaults)
+	}
+	if rc.HeaderFile != "" {
+		c.HeaderFile = rc.HeaderFile
+	}
+	if len(rc.Paths) > 0 {
+		c.Paths = rc.Paths
+	}
+	return c, nil
+}
+
+// loadCache loads the cache file of the Config, or the one at the module
+// root.
+func (rc Config) loadCache() (*cache.Cache, error) {
+	path := rc.CacheFile
+	if path == "" {
+		wd, err := rc.workingDir()
+		if err != nil {
+			return nil, err
+		}
+		root, err := config.ModuleRoot(wd)
+		if err != nil {
+			return nil, err
+		}
+		if root == "" {
+			root = wd
+		}
+		path = filepath.Join(root, cache.Filename)
+	}
+	return cache.Load(path)
+}
+
+// cacheInputs returns a hash of the inputs of generating code for the supplied
+// package variants; the version of the generators, their configuration and
+// header, and the sources, dependencies and build tags of each variant. The
+// files written by any configured generator are not considered inputs, except
+// those of the supplied generators.
+func cacheInputs(c *config.Config, version, header string, vs []Variant, inputs map[string]bool) (string, error) {
+	cfg, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot encode config")
+	}
+	generated := map[string]bool{}
+	for name, g := range c.Generators {
+		if !inputs[name] {
+			generated[g.Filename] = true
+		}
+	}
+	data := make([][]byte, 0, 2*len(vs))
+	for _, v := range vs {
+		in, err := cache.Inputs(v.Package, generated, []byte(version), cfg, []byte(header))
+		if err != nil {
+			return "", err
+		}
+		data = append(data, []byte(v.Tags), []byte(in))
+	}
+	return cache.Hash(data...), nil
+}
+
+// warnTypeErrors prints a warning for every tolerated type error of the
+// supplied package to the supplied writer.
+func warnTypeErrors(out io.Writer, p *packages.Package, typeErrorsOK bool) {
+	for _, err := range p.Errors {
+		if tolerated(err, typeErrorsOK) {
+			fmt.Fprintf(out, "warning: %s\n", err)
+		}
+	}
+}
+
+// exclusive returns true if more than one of the supplied flags is set.
+func exclusive(flags ...bool) bool {
+	set := 0
+	for _, f := range flags {
+		if f {
+			set++
+		}
+	}
+	return set > 1
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+const (
+	errReadGeneratedFile = "cannot read generated file"
+	errDiffGeneratedFile = "cannot diff generated file"
+	errStaleFiles        = "generated files are out of date, rerun ndd-gen"
+)
+
+// A verifier compares rendered files against their on-disk counterparts
+// instead of writing them. A unified diff is printed for every file that
+// differs.
+type verifier struct {
+	out   io.Writer
+	stale []string
+}
+
+func newVerifier(out io.Writer) *verifier {
+	return &verifier{out: out}
+}
+
+// Write is a generate.FileWriter that records drift between the supplied data
+// and the current contents of the supplied file.
+func (v *verifier) Write(file string, data []byte) error {
+	current, err := ioutil.ReadFile(file) // nolint:gosec
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, fmt.Sprintf("%s : %s", errReadGeneratedFile, file))
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+	return v.drift(file, current, data)
+}
+
+// Remove is a generate.FileRemover that records a previously generated file
+// that would be removed as drift.
+func (v *verifier) Remove(file string) error {
+	current, err := ioutil.ReadFile(file) // nolint:gosec
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s : %s", errReadGeneratedFile, file))
+	}
+	if !generate.IsGenerated(current) {
+		return nil
+	}
+	return v.drift(file, current, nil)
+}
+
+func (v *verifier) drift(file string, current, data []byte) error {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(data),
+		FromFile: file,
+		ToFile:   file + " (generated)",
+		Context:  3,
+	})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s : %s", errDiffGeneratedFile, file))
+	}
+	fmt.Fprint(v.out, diff)
+	v.stale = append(v.stale, file)
+	return nil
+}
+
+// Merge the stale files recorded by the supplied verifier into this one.
+func (v *verifier) Merge(o *verifier) {
+	v.stale = append(v.stale, o.stale...)
+}
+
+// Err returns an error listing every stale file, if any.
+func (v *verifier) Err() error {
+	if len(v.stale) == 0 {
+		return nil
+	}
+	sort.Strings(v.stale)
+	return errors.Errorf("%s : %v", errStaleFiles, v.stale)
+}
+
+// splitLines splits the supplied data into lines for diffing. Unlike
+// difflib.SplitLines it returns no lines for empty (e.g. missing) files.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return difflib.SplitLines(string(data))
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sample generates example manifests of custom resources.
+package sample
+
+import (
+	"bytes"
+	"go/types"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
+
+	"github.com/netw-device-driver/ndd-tools/internal/crd"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+)
+
+// Placeholder values of the manifests.
+const (
+	PlaceholderString      = "example"
+	PlaceholderDateTime    = "2021-01-01T00:00:00Z"
+	PlaceholderBytes       = "ZXhhbXBsZQ=="
+	PlaceholderKey         = "key"
+	PlaceholderNetworkNode = "network-node"
+)
+
+// NetworkNodeRef is the property of a managed resource's spec that refers to
+// its NetworkNode.
+const NetworkNodeRef = "networkNodeRef"
+
+// A Manifest is an example custom resource.
+type Manifest struct {
+	// Comment describing the manifest.
+	Comment string
+
+	// Object is the manifest itself.
+	Object yaml.MapSlice
+}
+
+// A Builder builds Manifests.
+type Builder struct {
+	schemas *crd.Schemas
+	group   string
+	version string
+}
+
+// NewBuilder returns a Builder that builds Manifests of the kinds of the
+// supplied package, which must declare its API group. See crd.GroupVersion.
+func NewBuilder(p *packages.Package) (*Builder, error) {
+	group, version, err := crd.GroupVersion(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{schemas: crd.NewSchemas(p), group: group, version: version}, nil
+}
+
+// Group returns the API group of the Manifests built by the Builder.
+func (b *Builder) Group() string {
+	return b.group
+}
+
+// Build returns a minimal and a fully populated Manifest of the supplied
+// managed resource. The minimal Manifest specifies only required fields. Both
+// refer to a NetworkNode.
+func (b *Builder) Build(o types.Object) (minimal, full *Manifest, err error) {
+	v, _, _ := types.LookupFieldOrMethod(o.Type(), true, nil, fields.NameSpec)
+	spec, ok := v.(*types.Var)
+	if !ok || !spec.IsField() {
+		return nil, nil, errors.Errorf("%s has no %s field", o.Name(), fields.NameSpec)
+	}
+	sch, err := b.schemas.For(spec.Type())
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot build schema of %s", o.Name())
+	}
+
+	manifest := func(name string, all bool) *Manifest {
+		s, _ := value(sch, all).(yaml.MapSlice)
+		s = append(yaml.MapSlice{{Key: NetworkNodeRef, Value: yaml.MapSlice{{Key: "name", Value: PlaceholderNetworkNode}}}}, without(s, NetworkNodeRef)...)
+		return &Manifest{Object: yaml.MapSlice{
+			{Key: "apiVersion", Value: b.group + "/" + b.version},
+			{Key: "kind", Value: o.Name()},
+			{Key: "metadata", Value: yaml.MapSlice{{Key: "name", Value: strings.ToLower(o.Name()) + "-" + name}}},
+			{Key: "spec", Value: s},
+		}}
+	}
+	minimal = manifest("minimal", false)
+	minimal.Comment = "A minimal " + o.Name() + ", specifying only its required fields."
+	full = manifest("full", true)
+	full.Comment = "A " + o.Name() + " specifying all of its fields."
+	return minimal, full, nil
+}
+
+// Marshal the supplied Manifests to a YAML stream, carrying
+// generate.HeaderGenerated.
+func Marshal(ms ...*Manifest) ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString("# " + generate.HeaderGenerated + "\n")
+	for _, m := range ms {
+		data, err := yaml.Marshal(m.Object)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot marshal manifest")
+		}
+		b.WriteString("---\n")
+		if m.Comment != "" {
+			b.WriteString("# " + m.Comment + "\n")
+		}
+		b.Write(data)
+	}
+	return b.Bytes(), nil
+}
+
+// value returns a sample value of the supplied schema. Objects include only
+// their required properties unless all is true.
+func value(s *crd.Schema, all bool) interface{} { // nolint:gocyclo
+	switch {
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case s.XIntOrString:
+		return 1
+	}
+
+	switch s.Type {
+	case "string":
+		return str(s)
+	case "boolean":
+		return true
+	case "integer":
+		return int64(number(s, 1))
+	case "number":
+		return number(s, 1.5)
+	case "array":
+		n := int64(1)
+		if s.MinItems != nil && *s.MinItems > n {
+			n = *s.MinItems
+		}
+		l := make([]interface{}, 0, n)
+		if s.Items == nil {
+			return l
+		}
+		for i := int64(0); i < n; i++ {
+			l = append(l, value(s.Items, all))
+		}
+		return l
+	case "object":
+		if s.AdditionalProperties != nil {
+			return yaml.MapSlice{{Key: PlaceholderKey, Value: value(s.AdditionalProperties, all)}}
+		}
+		return object(s, all)
+	}
+	return yaml.MapSlice{}
+}
+
+func object(s *crd.Schema, all bool) yaml.MapSlice {
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	names := make([]string, 0, len(s.Properties))
+	for n := range s.Properties {
+		if all || required[n] {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	o := yaml.MapSlice{}
+	for _, n := range names {
+		o = append(o, yaml.MapItem{Key: n, Value: value(s.Properties[n], all)})
+	}
+	return o
+}
+
+func str(s *crd.Schema) string {
+	switch s.Format {
+	case "date-time":
+		return PlaceholderDateTime
+	case "byte":
+		return PlaceholderBytes
+	}
+	v := PlaceholderString
+	if s.MinLength != nil && int64(len(v)) < *s.MinLength {
+		v += strings.Repeat("x", int(*s.MinLength)-len(v))
+	}
+	if s.MaxLength != nil && int64(len(v)) > *s.MaxLength {
+		v = v[:*s.MaxLength]
+	}
+	return v
+}
+
+// number returns the supplied placeholder, or the nearest value within the
+// bounds of the supplied schema.
+func number(s *crd.Schema, placeholder float64) float64 {
+	v := placeholder
+	if s.Minimum != nil && v <= *s.Minimum {
+		v = *s.Minimum
+		if s.ExclusiveMinimum {
+			v = math.Floor(v) + 1
+		}
+	}
+	if s.Maximum != nil && v >= *s.Maximum {
+		v = *s.Maximum
+		if s.ExclusiveMaximum {
+			v = math.Ceil(v) - 1
+		}
+	}
+	return v
+}
+
+func without(s yaml.MapSlice, key string) yaml.MapSlice {
+	out := make(yaml.MapSlice, 0, len(s))
+	for _, i := range s {
+		if i.Key != key {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/netw-device-driver/ndd-tools/pkg/gen"
+)
+
+// newHello returns a NewMethod that writes a Hello method, which returns a
+// greeting, using the supplied receiver name.
+func newHello(receiver string) gen.NewMethod {
+	return func(f *jen.File, o types.Object) {
+		f.Commentf("Hello returns a greeting from this %s.", o.Name())
+		f.Func().Params(jen.Id(receiver).Op("*").Id(o.Name())).Id("Hello").Params().String().Block(
+			jen.Return(jen.Lit("hello from " + o.Name())),
+		)
+	}
+}
+
+// This example generates a Hello method for every managed resource of the
+// module in testdata/example. It prints what it generates rather than writing
+// it, and loads the module offline because its dependencies are stubs.
+func Example() {
+	dir, err := filepath.Abs(filepath.Join("testdata", "example"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out := &bytes.Buffer{}
+
+	err = gen.Run(gen.Config{
+		Dir:    dir,
+		Env:    []string{"GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off"},
+		Paths:  []string{"./apis/..."},
+		DryRun: true,
+		Stdout: out,
+		Generators: []gen.Generator{{
+			Name:     "hello",
+			Defaults: gen.GeneratorConfig{Filename: "zz_generated.hello.go", Receiver: "mg"},
+			Generate: func(g gen.GeneratorConfig, i gen.Imports, header string, p *gen.Package, wo ...gen.WriteOption) error {
+				return gen.WriteMethods(p.Package, gen.MethodSet{"Hello": newHello(g.Receiver)},
+					filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+					append([]gen.WriteOption{gen.WithHeaders(header), gen.WithMatcher(p.Managed())}, wo...)...)
+			},
+		}},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(strings.ReplaceAll(out.String(), dir, "testdata/example"))
+	// Output:
+	// // ---- testdata/example/apis/v1/zz_generated.hello.go ----
+	// //go:build !ignore_autogenerated
+	// // +build !ignore_autogenerated
+	//
+	// // Code generated by ndd-gen. DO NOT EDIT.
+	//
+	// package v1
+	//
+	// // Hello returns a greeting from this Thing.
+	// func (mg *Thing) Hello() string {
+	// 	return "hello from Thing"
+	// }
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gen is the public API of ndd-gen, for composing custom generators.
+// Generators run using the same package loading, config file, caching and
+// file writing behavior as the ndd-gen commands, for example:
+//
+//	func main() {
+//		err := gen.Run(gen.Config{
+//			Paths: []string{"./apis/..."},
+//			Generators: []gen.Generator{{
+//				Name:     "hello",
+//				Defaults: gen.GeneratorConfig{Filename: "zz_generated.hello.go", Receiver: "mg"},
+//				Generate: func(g gen.GeneratorConfig, i gen.Imports, header string, p *gen.Package, wo ...gen.WriteOption) error {
+//					return gen.WriteMethods(p.Package, gen.MethodSet{"Hello": newHello(g.Receiver)},
+//						filepath.Join(filepath.Dir(p.GoFiles[0]), g.Filename),
+//						append([]gen.WriteOption{gen.WithHeaders(header), gen.WithMatcher(p.Managed())}, wo...)...)
+//				},
+//			}},
+//		})
+//		if err != nil {
+//			os.Exit(1)
+//		}
+//	}
+//
+// Here newHello returns the NewMethod that writes the Hello method; see the
+// package example.
+//
+// Many of the types of this package are those of ndd-gen's internal packages,
+// so its API may change between minor versions of ndd-tools until it is
+// declared stable.
+package gen
+
+import (
+	"github.com/netw-device-driver/ndd-tools/internal/config"
+	"github.com/netw-device-driver/ndd-tools/internal/runner"
+)
+
+// DisableMarker disables the generation of methods for the objects it marks
+// with the value false, e.g. +ndd:generate:methods=false. See Package.Enabled.
+const DisableMarker = runner.DisableMarker
+
+// A Config configures a run of generators. See Run.
+type Config = runner.Config
+
+// A Generator generates code for every configured package. Its Name is that of
+// the configuration of the generator in the config file.
+type Generator = runner.Generator
+
+// A GeneratorFunc generates code for the supplied package, per the supplied
+// configuration and imports. Generated files carry the supplied header, and
+// must be written using the supplied WriteOptions.
+type GeneratorFunc = runner.GeneratorFunc
+
+// A Package is a loaded package along with the state that is shared by all of
+// the generators that run for it; its comments and the objects for which
+// generation is enabled.
+type Package = runner.Package
+
+// A GeneratorConfig configures a single generator, e.g. the filename it writes.
+type GeneratorConfig = config.Generator
+
+// Imports used by generated code.
+type Imports = config.Imports
+
+// An Import is a Go import path and the alias used to refer to it in
+// generated code.
+type Import = config.Import
+
+// Run the generators of the supplied Config, in order, for every configured
+// package and write, verify or print what they generate.
+func Run(c Config) error {
+	return runner.Run(c)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/comments"
+	"github.com/netw-device-driver/ndd-tools/internal/fields"
+	"github.com/netw-device-driver/ndd-tools/internal/match"
+)
+
+// An Object matcher returns true if the supplied object matches.
+type Object = match.Object
+
+// Managed returns an Object matcher that returns true if the supplied Object
+// is a ndd managed resource.
+func Managed() Object { return match.Managed() }
+
+// ManagedList returns an Object matcher that returns true if the supplied
+// Object is a list of ndd managed resources.
+func ManagedList() Object { return match.ManagedList() }
+
+// NetworkNode returns an Object matcher that returns true if the supplied
+// Object is a NetworkNode.
+func NetworkNode() Object { return match.NetworkNode() }
+
+// NetworkNodeUsage returns an Object matcher that returns true if the supplied
+// Object is a NetworkNodeUsage.
+func NetworkNodeUsage() Object { return match.NetworkNodeUsage() }
+
+// NetworkNodeUsageList returns an Object matcher that returns true if the
+// supplied Object is a list of NetworkNode usages.
+func NetworkNodeUsageList() Object { return match.NetworkNodeUsageList() }
+
+// HasMarker returns an Object matcher that returns true if the supplied Object
+// has a comment marker k with the value v. Comment markers are read from the
+// supplied Comments.
+func HasMarker(c Comments, k, v string) Object { return match.HasMarker(c, k, v) }
+
+// DoesNotHaveMarker returns an Object matcher that returns true if the
+// supplied Object does not have a comment marker k with the value v.
+func DoesNotHaveMarker(c Comments, k, v string) Object { return match.DoesNotHaveMarker(c, k, v) }
+
+// AllOf returns an Object matcher that returns true if all of the supplied
+// Object matchers return true.
+func AllOf(m ...Object) Object { return match.AllOf(m...) }
+
+// AnyOf returns an Object matcher that returns true if any of the supplied
+// Object matchers return true.
+func AnyOf(m ...Object) Object { return match.AnyOf(m...) }
+
+// A Matcher is a function that returns true if the supplied struct field
+// matches.
+type Matcher = fields.Matcher
+
+// HasFields returns true if the supplied Object is a struct with fields that
+// match all of the supplied Matchers.
+func HasFields(o types.Object, m ...Matcher) bool { return fields.Has(o, m...) }
+
+// IsEmbedded returns a Matcher that returns true if the supplied field is
+// embedded.
+func IsEmbedded() Matcher { return fields.IsEmbedded() }
+
+// IsSlice returns a Matcher that returns true if the supplied field is a slice.
+func IsSlice() Matcher { return fields.IsSlice() }
+
+// IsNamed returns a Matcher that returns true if the supplied field has the
+// supplied name.
+func IsNamed(name string) Matcher { return fields.IsNamed(name) }
+
+// IsTypeNamed returns a Matcher that returns true if the supplied field has the
+// supplied type name suffix and name.
+func IsTypeNamed(typeNameSuffix, name string) Matcher {
+	return fields.IsTypeNamed(typeNameSuffix, name)
+}
+
+// HasFieldThat returns a Matcher that returns true if the supplied field is a
+// struct that matches the supplied field matchers.
+func HasFieldThat(m ...Matcher) Matcher { return fields.HasFieldThat(m...) }
+
+// IsSpec returns a Matcher that returns true if the supplied field appears to
+// be a Kubernetes resource spec.
+func IsSpec() Matcher { return fields.IsSpec() }
+
+// IsStatus returns a Matcher that returns true if the supplied field appears to
+// be a Kubernetes resource status.
+func IsStatus() Matcher { return fields.IsStatus() }
+
+// Comments of a package, by the objects and fields they document.
+type Comments = comments.Comments
+
+// CommentsIn returns all comments in the supplied package.
+func CommentsIn(p *packages.Package) Comments { return comments.In(p) }
+
+// Markers are comments that begin with a special character, e.g.
+// +ndd:key or +kubebuilder:default=1. Markers that contain '=' are key=value
+// pairs, represented as one map key with a slice of multiple values.
+type Markers = comments.Markers
+
+// ParseMarkers parses the comment markers of the supplied comment, e.g. one
+// returned by Comments.For.
+func ParseMarkers(comment string) Markers { return comments.ParseMarkers(comment) }
@@ -0,0 +1,26 @@
+// Package v1 declares a managed resource for the example of package gen.
+package v1
+
+import (
+	nddv1 "github.com/netw-device-driver/ndd-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ThingSpec defines the desired state of a Thing.
+type ThingSpec struct {
+	nddv1.ResourceSpec `json:",inline"`
+}
+
+// ThingStatus represents the observed state of a Thing.
+type ThingStatus struct {
+	nddv1.ResourceStatus `json:",inline"`
+}
+
+// A Thing is a managed resource.
+type Thing struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ThingSpec   `json:"spec,omitempty"`
+	Status ThingStatus `json:"status,omitempty"`
+}
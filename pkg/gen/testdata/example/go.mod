@@ -0,0 +1,14 @@
+module example.org/example
+
+go 1.16
+
+require (
+	github.com/netw-device-driver/ndd-runtime v0.0.0
+	k8s.io/apimachinery v0.0.0
+)
+
+// The dependencies of the example are the stubs used by the tests of the
+// ndd-gen commands.
+replace github.com/netw-device-driver/ndd-runtime => ../../../../cmd/nddgen/testdata/stubs/ndd-runtime
+
+replace k8s.io/apimachinery => ../../../../cmd/nddgen/testdata/stubs/apimachinery
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 Wim Henderickx.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen
+
+import (
+	"go/token"
+
+	"github.com/dave/jennifer/jen"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/netw-device-driver/ndd-tools/internal/generate"
+	"github.com/netw-device-driver/ndd-tools/internal/method"
+)
+
+// A NewMethod writes a method for the supplied object to the supplied file.
+type NewMethod = method.New
+
+// A MethodSet is a set of NewMethods, keyed by the name of the method they
+// write.
+type MethodSet = method.Set
+
+// A MethodFilter returns true if the named method should not be written for
+// the supplied object.
+type MethodFilter = method.Filter
+
+// DefinedOutside returns a MethodFilter that returns true if the supplied
+// object has a method of the supplied name that is defined outside of the
+// supplied filename, e.g. by hand.
+func DefinedOutside(fs *token.FileSet, filename string) MethodFilter {
+	return method.DefinedOutside(fs, filename)
+}
+
+// A WriteOption configures how generated files are written.
+type WriteOption = generate.WriteOption
+
+// A FileWriter persists the rendered contents of a generated file.
+type FileWriter = generate.FileWriter
+
+// A FileRemover removes a previously generated file that would no longer
+// contain any declarations.
+type FileRemover = generate.FileRemover
+
+// WithHeaders specifies strings to be written as comments to the generated
+// file, above the package definition.
+func WithHeaders(h ...string) WriteOption { return generate.WithHeaders(h...) }
+
+// WithMatcher specifies an Object matcher that is used to filter the objects
+// of the package down to the set that need the generated methods.
+func WithMatcher(m Object) WriteOption { return generate.WithMatcher(m) }
+
+// WithImportAliases specifies the aliases, by import path, used to refer to
+// packages in generated code.
+func WithImportAliases(ia map[string]string) WriteOption { return generate.WithImportAliases(ia) }
+
+// WithPackageName specifies the name of the package of the generated file,
+// for files that are written to a package other than the one whose code they
+// are generated from.
+func WithPackageName(name string) WriteOption { return generate.WithPackageName(name) }
+
+// WriteMethods writes the supplied methods for each object of the supplied
+// package to the supplied file. Methods are not written if a method with the
+// same name is defined for the object outside of the file. Files that would
+// contain no methods are not written, and previously generated ones removed.
+// Files that were not generated by ndd-gen are never overwritten.
+func WriteMethods(p *packages.Package, ms MethodSet, file string, wo ...WriteOption) error {
+	return generate.WriteMethods(p, ms, file, wo...)
+}
+
+// WriteCode writes the code added to a file by the supplied function to the
+// supplied file, which belongs to the supplied package, as WriteMethods does.
+func WriteCode(p *packages.Package, file string, code func(f *jen.File), wo ...WriteOption) error {
+	return generate.WriteCode(p, file, code, wo...)
+}